@@ -1,8 +1,10 @@
 package ghttp
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 )
 
@@ -39,29 +41,55 @@ func GCookie(simple map[string]string) []*http.Cookie {
 	return cookies
 }
 
-// GGet 构造一个简单的GET请求协议
+// 复制一份header，避免外部修改影响client内部的快照
+func copyHeader(header map[string]string) map[string]string {
+	if header == nil {
+		return nil
+	}
+	newHeader := make(map[string]string, len(header))
+	for k, v := range header {
+		newHeader[k] = v
+	}
+	return newHeader
+}
+
+// 复制一份cookie切片，避免外部修改影响client内部的快照
+func copyCookies(cookies []*http.Cookie) []*http.Cookie {
+	if cookies == nil {
+		return nil
+	}
+	return append(make([]*http.Cookie, 0, len(cookies)), cookies...)
+}
+
+// GGet 构造一个简单的GET请求协议，参数按key排序
+// 需要多值参数或struct参数时，使用 GQuery
 func GGet(strUrl string, values map[string]string) string {
 	if strUrl == "" || values == nil {
 		return strUrl
 	}
 	var buf strings.Builder
 	buf.WriteString(strUrl)
-	buf.WriteByte('?')
-	i := 0
-	for k, v := range values {
+	//url中已有query时，以 & 追加
+	switch {
+	case !strings.Contains(strUrl, "?"):
+		buf.WriteByte('?')
+	case !strings.HasSuffix(strUrl, "?") && !strings.HasSuffix(strUrl, "&"):
+		buf.WriteByte('&')
+	}
+	for i, k := range sortedKeys(values) {
 		if i > 0 {
 			buf.WriteByte('&')
 		}
 		buf.WriteString(url.QueryEscape(k))
 		buf.WriteByte('=')
-		buf.WriteString(url.QueryEscape(v))
-		i++
+		buf.WriteString(url.QueryEscape(values[k]))
 	}
 	return buf.String()
 }
 
 // GPostData 构造一个简单的POST body
 // 在form-data中，我没有允许一个值为数组的key，因为我觉得，这样好恶心，还不如直接调用json请求方式
+// 确实需要多值的key时，使用 GForm 从struct编码
 func GPostData(values map[string]string) url.Values {
 	if values == nil {
 		return nil
@@ -72,3 +100,78 @@ func GPostData(values map[string]string) url.Values {
 	}
 	return value
 }
+
+// 返回排序后的key，用于固定map的遍历顺序
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GPath 替换路径模板中的参数，如 GPath("/users/{id}", map[string]string{"id": "1"}) => /users/1
+// 参数值会进行路径转义，模板中存在未替换的参数时返回错误
+func GPath(template string, params map[string]string) (string, error) {
+	var buf strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			buf.WriteString(template)
+			return buf.String(), nil
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("ghttp: unclosed path param in %q", template)
+		}
+		end += start
+
+		name := template[start+1 : end]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("ghttp: missing path param %q", name)
+		}
+		buf.WriteString(template[:start])
+		buf.WriteString(url.PathEscape(value))
+		template = template[end+1:]
+	}
+}
+
+// 将相对url拼接到baseURL之后，url为绝对地址时原样返回
+// 与 url.ResolveReference 不同，baseURL 的路径始终保留，如 http://host/v1 + /users => http://host/v1/users
+// baseURL 与 url 的query会合并，同名参数以 url 为准
+func resolveURL(base *url.URL, rawUrl string) (string, error) {
+	if base == nil {
+		return rawUrl, nil
+	}
+	ref, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+	if ref.IsAbs() || ref.Host != "" {
+		return rawUrl, nil
+	}
+
+	u := *base
+	if ref.Path != "" || ref.RawPath != "" {
+		u.Path = strings.TrimSuffix(base.Path, "/") + "/" + strings.TrimPrefix(ref.Path, "/")
+		u.RawPath = ""
+		if ref.RawPath != "" || base.RawPath != "" {
+			u.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + "/" + strings.TrimPrefix(ref.EscapedPath(), "/")
+		}
+	}
+	if ref.RawQuery != "" {
+		if base.RawQuery == "" {
+			u.RawQuery = ref.RawQuery
+		} else {
+			query := base.Query()
+			for k, v := range ref.Query() {
+				query[k] = v
+			}
+			u.RawQuery = query.Encode()
+		}
+	}
+	u.Fragment = ref.Fragment
+	return u.String(), nil
+}
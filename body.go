@@ -0,0 +1,73 @@
+package ghttp
+
+import (
+	"io"
+	"net/url"
+)
+
+// Body 请求body的编码器，将body设置到 Request 中
+type Body interface {
+	Apply(r *Request)
+}
+
+// BodyFunc 函数形式的 Body
+type BodyFunc func(r *Request)
+
+func (f BodyFunc) Apply(r *Request) {
+	f(r)
+}
+
+// JsonBody json编码的body
+func JsonBody(value interface{}) Body {
+	return BodyFunc(func(r *Request) {
+		r.SetJsonBody(value)
+	})
+}
+
+// XmlBody xml编码的body
+func XmlBody(value interface{}) Body {
+	return BodyFunc(func(r *Request) {
+		r.SetXmlBody(value)
+	})
+}
+
+// CodecBody 使用 contentType 对应的编解码器编码的body，如 CodecBody(ghttp.HTTP_CONTENT_TYPE_MSGPACK, value)
+func CodecBody(contentType string, value interface{}) Body {
+	return BodyFunc(func(r *Request) {
+		r.SetCodecBody(contentType, value)
+	})
+}
+
+// FormBody form编码的body
+func FormBody(values url.Values) Body {
+	return BodyFunc(func(r *Request) {
+		r.SetFormData(values)
+	})
+}
+
+// BytesBody []byte形式的body，contentType 为空时不设置 Content-Type
+func BytesBody(value []byte, contentType string) Body {
+	return BodyFunc(func(r *Request) {
+		r.SetBodyBytes(value)
+		if contentType != "" {
+			r.SetContentType(contentType)
+		}
+	})
+}
+
+// ReaderBody io.Reader形式的body，contentType 为空时不设置 Content-Type
+func ReaderBody(reader io.Reader, contentType string) Body {
+	return BodyFunc(func(r *Request) {
+		r.SetBody(reader)
+		if contentType != "" {
+			r.SetContentType(contentType)
+		}
+	})
+}
+
+// MultipartBody multipart形式的body
+func MultipartBody(body IMultipart) Body {
+	return BodyFunc(func(r *Request) {
+		r.SetMultipart(body)
+	})
+}
@@ -0,0 +1,342 @@
+package ghttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器打开时返回的错误，可通过 errors.Is 判断
+var ErrCircuitOpen = errors.New("ghttp: circuit breaker is open")
+
+// CircuitOpenError 熔断器打开时 IResponse.Error() 返回的错误
+type CircuitOpenError struct {
+	//熔断器的分组key
+	Key string
+
+	//距离进入半开状态的剩余时间，半开状态下探测请求已满时为0
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Key == "" {
+		return ErrCircuitOpen.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrCircuitOpen.Error(), e.Key)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState 熔断器状态
+type CircuitState int8
+
+// CircuitState 的枚举
+const (
+	//关闭，请求正常发送并统计失败率
+	CircuitClosed CircuitState = iota
+
+	//打开，请求直接返回 ErrCircuitOpen
+	CircuitOpen
+
+	//半开，允许少量探测请求，全部成功后关闭，任一失败则重新打开
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int8(s))
+}
+
+// 熔断器的默认设置
+const (
+	defaultCircuitWindow           = 10 * time.Second
+	defaultCircuitMinRequests      = 10
+	defaultCircuitFailureRatio     = 0.5
+	defaultCircuitCooldown         = 30 * time.Second
+	defaultCircuitHalfOpenRequests = 1
+	defaultCircuitIdleTimeout      = 5 * time.Minute
+
+	//滚动窗口划分的桶数
+	circuitWindowBuckets = 10
+)
+
+// CircuitBreakerOptions 熔断设置，零值字段使用默认值
+type CircuitBreakerOptions struct {
+	//分组函数，nil 时整个client共用一个熔断器，按host分组可使用 ByHost
+	KeyFunc func(request *http.Request) string
+
+	//统计失败率的滚动窗口，默认10s
+	Window time.Duration
+
+	//窗口内的请求数达到该值后才判断失败率，默认10
+	MinRequests int
+
+	//窗口内失败率达到该值时打开熔断器，默认0.5
+	FailureRatio float64
+
+	//打开后经过该时长进入半开状态，默认30s
+	Cooldown time.Duration
+
+	//半开状态允许的探测请求数，默认1
+	HalfOpenRequests int
+
+	//判断响应是否失败，默认使用 DefaultCircuitFailure
+	IsFailure func(resp IResponse) bool
+
+	//状态变化时的回调，同步调用，不要在其中执行耗时操作
+	OnStateChange func(key string, from, to CircuitState)
+
+	//关闭状态的熔断器超过该时长没有请求时删除，打开及半开状态的保留以免丢失熔断，默认5分钟，小于 Window 时按 Window 处理
+	IdleTimeout time.Duration
+}
+
+// DefaultCircuitFailure 默认的失败判断
+// 传输错误（含超时，调用方取消ctx除外）及 5xx 视为失败
+func DefaultCircuitFailure(resp IResponse) bool {
+	var httpErr *HTTPError
+	if err := resp.Error(); err != nil && !errors.As(err, &httpErr) {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode() >= 500
+}
+
+// 滚动窗口中的一个桶
+type circuitBucket struct {
+	//桶对应的时间序号
+	epoch    int64
+	total    int
+	failures int
+}
+
+// 单个分组的熔断器
+type circuitBreaker struct {
+	key     string
+	options *CircuitBreakerOptions
+
+	mu    sync.Mutex
+	state CircuitState
+
+	//状态每变化一次加1，用于忽略状态变化前发出的请求
+	generation uint64
+
+	openedAt time.Time
+
+	//半开状态下进行中及已成功的探测请求数
+	probing   int
+	succeeded int
+
+	buckets []circuitBucket
+}
+
+// 处于关闭状态，空闲超过 Window 后窗口内已无统计，此时删除与新建的熔断器等价
+func (b *circuitBreaker) closed(time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == CircuitClosed
+}
+
+// 状态变化，在释放锁后回调
+type circuitTransition struct {
+	from, to CircuitState
+}
+
+// 判断是否允许发送请求，允许时返回当前的 generation
+func (b *circuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	var transitions []circuitTransition
+	defer func() {
+		b.mu.Unlock()
+		b.notify(transitions)
+	}()
+
+	if b.state == CircuitOpen {
+		if wait := b.options.Cooldown - time.Since(b.openedAt); wait > 0 {
+			return 0, &CircuitOpenError{Key: b.key, RetryAfter: wait}
+		}
+		transitions = append(transitions, b.setState(CircuitHalfOpen))
+	}
+	if b.state == CircuitHalfOpen {
+		if b.probing+b.succeeded >= b.options.HalfOpenRequests {
+			return 0, &CircuitOpenError{Key: b.key}
+		}
+		b.probing++
+	}
+	return b.generation, nil
+}
+
+// 记录请求结果
+func (b *circuitBreaker) record(generation uint64, failure bool) {
+	b.mu.Lock()
+	var transitions []circuitTransition
+	defer func() {
+		b.mu.Unlock()
+		b.notify(transitions)
+	}()
+
+	//请求发出后状态已变化，结果不再计入
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case CircuitHalfOpen:
+		b.probing--
+		if failure {
+			transitions = append(transitions, b.setState(CircuitOpen))
+			return
+		}
+		b.succeeded++
+		if b.succeeded >= b.options.HalfOpenRequests {
+			transitions = append(transitions, b.setState(CircuitClosed))
+		}
+	case CircuitClosed:
+		total, failures := b.count(failure)
+		if total >= b.options.MinRequests && float64(failures) >= float64(total)*b.options.FailureRatio {
+			transitions = append(transitions, b.setState(CircuitOpen))
+		}
+	}
+}
+
+// 释放请求占用的探测名额，不计入结果，用于请求被取消的情况
+func (b *circuitBreaker) release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation == b.generation && b.state == CircuitHalfOpen {
+		b.probing--
+	}
+}
+
+// 将本次结果写入滚动窗口，返回窗口内的请求数及失败数
+func (b *circuitBreaker) count(failure bool) (total, failures int) {
+	size := b.options.Window / circuitWindowBuckets
+	if size <= 0 {
+		size = 1
+	}
+	epoch := time.Now().UnixNano() / int64(size)
+
+	bucket := &b.buckets[epoch%circuitWindowBuckets]
+	if bucket.epoch != epoch {
+		*bucket = circuitBucket{epoch: epoch}
+	}
+	bucket.total++
+	if failure {
+		bucket.failures++
+	}
+
+	for _, bucket := range b.buckets {
+		if epoch-bucket.epoch < circuitWindowBuckets {
+			total += bucket.total
+			failures += bucket.failures
+		}
+	}
+	return total, failures
+}
+
+func (b *circuitBreaker) setState(state CircuitState) circuitTransition {
+	transition := circuitTransition{from: b.state, to: state}
+	b.state = state
+	b.generation++
+	b.probing = 0
+	b.succeeded = 0
+	switch state {
+	case CircuitOpen:
+		b.openedAt = time.Now()
+	case CircuitClosed:
+		b.buckets = make([]circuitBucket, circuitWindowBuckets)
+	}
+	return transition
+}
+
+func (b *circuitBreaker) notify(transitions []circuitTransition) {
+	if b.options.OnStateChange == nil {
+		return
+	}
+	for _, t := range transitions {
+		b.options.OnStateChange(b.key, t.from, t.to)
+	}
+}
+
+// 按分组管理熔断器
+type circuitBreakers struct {
+	options CircuitBreakerOptions
+
+	breakers *keyedGroups[*circuitBreaker]
+}
+
+func newCircuitBreakers(options CircuitBreakerOptions) *circuitBreakers {
+	if options.Window <= 0 {
+		options.Window = defaultCircuitWindow
+	}
+	if options.MinRequests <= 0 {
+		options.MinRequests = defaultCircuitMinRequests
+	}
+	if options.FailureRatio <= 0 {
+		options.FailureRatio = defaultCircuitFailureRatio
+	}
+	if options.Cooldown <= 0 {
+		options.Cooldown = defaultCircuitCooldown
+	}
+	if options.HalfOpenRequests <= 0 {
+		options.HalfOpenRequests = defaultCircuitHalfOpenRequests
+	}
+	if options.IsFailure == nil {
+		options.IsFailure = DefaultCircuitFailure
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = defaultCircuitIdleTimeout
+	}
+	if options.IdleTimeout < options.Window {
+		options.IdleTimeout = options.Window
+	}
+	m := &circuitBreakers{options: options}
+	m.breakers = newKeyedGroups(options.IdleTimeout, func(key string) *circuitBreaker {
+		return &circuitBreaker{
+			key:     key,
+			options: &m.options,
+			buckets: make([]circuitBucket, circuitWindowBuckets),
+		}
+	}, (*circuitBreaker).closed)
+	return m
+}
+
+func (m *circuitBreakers) breaker(request *http.Request) *circuitBreaker {
+	key := ""
+	if m.options.KeyFunc != nil {
+		key = m.options.KeyFunc(request)
+	}
+
+	return m.breakers.get(key)
+}
+
+// 内置中间件：熔断，每次尝试（含重试）都单独计入
+// 只有实际发出的请求计入结果，限流等待超时、失败等未发出请求的情况及调用方取消的请求只释放探测名额
+func (c *client) circuitBreakerMiddleware(next Handler) Handler {
+	return func(ctx context.Context, request *http.Request) IResponse {
+		breaker := c.circuitBreakers.breaker(request)
+		generation, err := breaker.allow()
+		if err != nil {
+			return c.errorResponse(ctx, err)
+		}
+
+		sent := new(bool)
+		resp := next(context.WithValue(ctx, ctxKeySent, sent), request)
+		if !*sent || errors.Is(resp.Error(), context.Canceled) {
+			breaker.release(generation)
+		} else {
+			breaker.record(generation, breaker.options.IsFailure(resp))
+		}
+		return resp
+	}
+}
@@ -3,15 +3,14 @@ package ghttp
 import (
 	"bytes"
 	"context"
-	"encoding/json"
-	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,6 +18,9 @@ type client struct {
 	//超时时间
 	timeOut time.Duration
 
+	//保护 header、cookies，二者采用写时复制，发起请求时读取的是一份一致的快照
+	globalMu sync.RWMutex
+
 	//Header请求头信息
 	header map[string]string
 
@@ -28,7 +30,7 @@ type client struct {
 	//golang内置client
 	client *http.Client
 
-	//临时header请求头, 仅本次请求生效
+	//临时header请求头, 仅本次请求生效, 并发安全的用法见 client.R()
 	_header map[string]string
 
 	//临时cookie, 仅本次请求生效
@@ -41,94 +43,344 @@ type client struct {
 	loggerFilePath string
 	loggerWriter   io.Writer
 
+	// 日志文件及写入该文件的日志输出，未设置日志目录时为 nil
+	logFile    *logFileRef
+	fileLogger Logger
+
+	// 写入 loggerWriter 的控制台日志输出，debug模式下创建，所有请求共用以保证并发写入安全
+	consoleLogger Logger
+
+	// 内置日志输出（文件、控制台）的最低级别
+	logLevel slog.Level
+
+	// 按请求结果使用的日志级别
+	logLevels LogLevels
+
+	// 自定义的日志输出，nil 表示不输出
+	customLogger Logger
+
+	// 日志脱敏规则，对所有日志输出生效
+	redactor *redactor
+
+	// 请求失败时是否记录日志
+	logErrors bool
+
+	// 日志中body的最大长度，<=0 表示不限制
+	logBodyLimit int
+
+	// 日志中是否记录请求、响应body
+	logRequestBody  bool
+	logResponseBody bool
+
 	// 调试开关
 	debugMode bool
 
 	// 请求唯一id
 	uniqueId string
+
+	// 重试策略，nil 表示不重试
+	retry *retryPolicy
+
+	// 由中间件链包装后的请求处理函数
+	handler Handler
+
+	// 判断状态码是否视为错误，nil 表示只有传输错误才视为错误
+	errorOnStatus func(statusCode int) bool
+
+	// 按 Content-Type 注册的编解码器
+	codecs codecRegistry
+
+	// 基础地址，nil 表示不拼接
+	baseURL *url.URL
+
+	// 限流器，nil 表示不限流
+	rateLimiter *rateLimiter
+
+	// 熔断器，nil 表示不熔断
+	circuitBreakers *circuitBreakers
+
+	// 异步请求执行器
+	executor *executor
+}
+
+// Wait 等待所有异步请求及其回调执行完毕
+func (c *client) Wait() {
+	_ = c.executor.wait(context.Background())
+}
+
+// Shutdown 停止接收新的异步请求，并等待已提交的异步请求及其回调执行完毕，之后关闭日志文件
+// ctx 结束时不再等待，返回ctx的错误；之后发起的异步请求返回 ErrClientShutdown，日志不再写入文件
+func (c *client) Shutdown(ctx context.Context) error {
+	err := c.executor.shutdown(ctx)
+	if c.logFile != nil {
+		if closeErr := c.logFile.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 // SetHeaderCache 临时header设置，仅本次请求生效
+// 并发不安全，多个goroutine共用client时临时header会串到其他请求中
+//
+// Deprecated: 使用 client.R().SetHeaders(header) 构造单次请求
 func (c *client) SetHeaderCache(header map[string]string) *client {
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
 	c._header = header
 	return c
 }
 
 // AddGlobalHeader 追加请求头，全生命周期有效
-// 并发不安全
 func (c *client) AddGlobalHeader(header map[string]string) {
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
+
+	newHeader := make(map[string]string, len(c.header)+len(header))
+	for k, v := range c.header {
+		newHeader[k] = v
+	}
 	for k, v := range header {
-		c.header[k] = v
+		newHeader[k] = v
 	}
+	c.header = newHeader
 }
 
 // ResetGlobalHeader 重置请求头，全生命周期有效
-// 并发不安全
 func (c *client) ResetGlobalHeader(header map[string]string) {
-	c.header = header
+	newHeader := copyHeader(header)
+
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
+	c.header = newHeader
+}
+
+// RemoveGlobalHeader 删除请求头，key 不区分大小写，全生命周期有效
+func (c *client) RemoveGlobalHeader(keys ...string) {
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
+
+	newHeader := make(map[string]string, len(c.header))
+	for k, v := range c.header {
+		newHeader[k] = v
+		for _, key := range keys {
+			if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(key) {
+				delete(newHeader, k)
+				break
+			}
+		}
+	}
+	c.header = newHeader
 }
 
 // SetCookiesCache 临时cookie设置，仅本次请求有效效
+// 并发不安全，多个goroutine共用client时临时cookie会串到其他请求中
+//
+// Deprecated: 使用 client.R().SetCookies(cookies) 构造单次请求
 func (c *client) SetCookiesCache(cookies []*http.Cookie) *client {
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
 	c._cookies = cookies
 	return c
 }
 
 // AddGlobalCookies 追加cookie，全生命周期有效
-// 并发不安全
 func (c *client) AddGlobalCookies(cookies []*http.Cookie) {
-	for _, cookie := range cookies {
-		c.cookies = append(c.cookies, cookie)
-	}
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
+
+	newCookies := make([]*http.Cookie, 0, len(c.cookies)+len(cookies))
+	newCookies = append(newCookies, c.cookies...)
+	newCookies = append(newCookies, cookies...)
+	c.cookies = newCookies
 }
 
 // ResetGlobalCookies 重设cookie，全生命周期有效
-// 并发不安全
 func (c *client) ResetGlobalCookies(cookies []*http.Cookie) {
-	c.cookies = cookies
+	newCookies := copyCookies(cookies)
+
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
+	c.cookies = newCookies
+}
+
+// RemoveGlobalCookie 按名称删除cookie，全生命周期有效
+func (c *client) RemoveGlobalCookie(names ...string) {
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
+
+	newCookies := make([]*http.Cookie, 0, len(c.cookies))
+	for _, cookie := range c.cookies {
+		removed := false
+		for _, name := range names {
+			if cookie.Name == name {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			newCookies = append(newCookies, cookie)
+		}
+	}
+	c.cookies = newCookies
+}
+
+// 获取全局header、cookie的快照，快照只读，不允许修改
+func (c *client) globalSnapshot() (map[string]string, []*http.Cookie) {
+	c.globalMu.RLock()
+	defer c.globalMu.RUnlock()
+	return c.header, c.cookies
 }
 
-// SetUniqueId 设置请求的uniqueId
+// SetUniqueId 设置之后所有请求日志中的uniqueId，单次请求使用 client.R().SetUniqueId(id)
 func (c *client) SetUniqueId(id string) {
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
 	c.uniqueId = id
 }
 
-// 初始化一个request
-func (c *client) getRequest(method, url string, body io.Reader) (*http.Request, error) {
-	request, err := http.NewRequest(method, url, body)
+// 获取请求的uniqueId，优先使用 Request.SetUniqueId 设置在ctx中的值
+func (c *client) requestUniqueId(ctx context.Context) string {
+	if id, ok := ctx.Value(ctxKeyUniqueId).(string); ok {
+		return id
+	}
+	c.globalMu.RLock()
+	defer c.globalMu.RUnlock()
+	return c.uniqueId
+}
+
+// 初始化一个request，ctx 会绑定到 request 上，用于取消、超时控制及传值
+// header、cookies 为本次请求的临时数据，header 会覆盖同名的全局header
+func (c *client) getRequest(ctx context.Context, method, url string, body io.Reader, header map[string]string, cookies []*http.Cookie) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	setSeekableBody(request, body)
 
-	for k, v := range c.header {
+	globalHeader, globalCookies := c.globalSnapshot()
+
+	for k, v := range globalHeader {
 		request.Header.Set(k, v)
 	}
-	for k, v := range c._header {
+	for k, v := range header {
 		request.Header.Set(k, v)
 	}
-	c._header = nil
 
 	if _, ok := request.Header["User-Agent"]; !ok {
 		request.Header.Set("User-Agent", HTTP_USER_AGENT_CHROME_PC)
 	}
 
-	for _, v := range c.cookies {
+	for _, v := range globalCookies {
 		request.AddCookie(v)
 	}
-	for _, v := range c._cookies {
+	for _, v := range cookies {
 		request.AddCookie(v)
 	}
-	c._cookies = nil
 
 	return request, nil
 }
 
+// body 支持 Seek 时，补充 GetBody 及 ContentLength，使请求在重试、重定向时可重新读取body
+func setSeekableBody(request *http.Request, body io.Reader) {
+	if request.GetBody != nil {
+		return
+	}
+	if rewinder, ok := body.(bodyRewinder); ok {
+		setRewindableBody(request, rewinder)
+		return
+	}
+	seeker, ok := body.(io.ReadSeeker)
+	if !ok {
+		return
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	if end, err := seeker.Seek(0, io.SeekEnd); err == nil {
+		request.ContentLength = end - start
+	}
+	if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+		return
+	}
+	request.GetBody = func() (io.ReadCloser, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(seeker), nil
+	}
+}
+
+// 可重置读取位置的body，如 StreamMultipart
+type bodyRewinder interface {
+	io.Reader
+
+	// ContentLength 返回body的总长度，未知时返回 -1
+	ContentLength() int64
+
+	rewind() error
+}
+
+// 长度已知时设置 ContentLength，否则以 chunked 方式发送
+func setRewindableBody(request *http.Request, body bodyRewinder) {
+	if length := body.ContentLength(); length >= 0 {
+		request.ContentLength = length
+		if length == 0 {
+			request.Body = http.NoBody
+		}
+	}
+	request.GetBody = func() (io.ReadCloser, error) {
+		if err := body.rewind(); err != nil {
+			return nil, err
+		}
+		if rc, ok := body.(io.ReadCloser); ok {
+			return rc, nil
+		}
+		return io.NopCloser(body), nil
+	}
+}
+
+// 根据 Content-Type 获取编解码器
+func (c *client) codec(contentType string) (Codec, error) {
+	codec, ok := c.codecs.lookup(contentType)
+	if !ok {
+		return nil, fmt.Errorf("ghttp: no codec registered for %q", contentType)
+	}
+	return codec, nil
+}
+
+// 使用 Content-Type 对应的编解码器编码
+func (c *client) marshal(contentType string, value interface{}) ([]byte, error) {
+	codec, err := c.codec(contentType)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Marshal(value)
+}
+
+// 兼容 SetHeaderCache、SetCookiesCache 的用法，取出临时header及cookie后立即清空
+func (c *client) legacyRequest(ctx context.Context, body io.Reader, setContentType ContentTypeFunc) *Request {
+	r := c.R().SetContext(ctx).SetBody(body)
+	r.setContentType = setContentType
+
+	c.globalMu.Lock()
+	defer c.globalMu.Unlock()
+	r.header, r.cookies = c._header, c._cookies
+	c._header, c._cookies = nil, nil
+	return r
+}
+
 // 封装http请求
 func (c *client) doRequest(ctx context.Context, r *http.Request) (context.Context, *http.Response, error) {
-	// 记录请求开始时间
-	ctx = c.buildStartTime(ctx)
-	response, err := c.client.Do(r)
-	return ctx, response, err
+	response, err := c.client.Do(r.WithContext(ctx))
+	return ctx, response, classifyError(err)
+}
+
+// 请求未发出时的错误响应，由 buildResponse 构造
+func (c *client) errorResponse(ctx context.Context, err error) IResponse {
+	_, resp := c.buildResponse(withDefaultContext(ctx), nil, err)
+	return resp
 }
 
 // POST请求中,处理request的函数
@@ -148,58 +400,60 @@ func setRequestPostXml(r *http.Request) {
 
 // 常规发起http请求
 func (c *client) sendWithMethod(ctx context.Context, method, url string, body io.Reader, setContentType ContentTypeFunc) IResponse {
-	request, err := c.getRequest(method, url, body)
-	if err != nil {
-		return c.logger(c.buildResponse(nil, nil, err))
-	}
-	if setContentType != nil {
-		setContentType(request)
-	}
-
-	return c.logger(c.buildResponse(c.doRequest(ctx, request)))
+	return c.legacyRequest(ctx, body, setContentType).Execute(method, url)
 }
 
 // 发起异步回调处理的请求
 func (c *client) sendWithMethodCallback(ctx context.Context, method, url string, body io.Reader, setContentType ContentTypeFunc, callback func(response IResponse)) error {
-	request, err := c.getRequest(method, url, body)
-	if err != nil {
-		return err
-	}
-	if setContentType != nil {
-		setContentType(request)
-	}
-
-	go func() {
-		callback(c.logger(c.buildResponse(c.doRequest(ctx, request))))
-	}()
-	return nil
+	return c.legacyRequest(ctx, body, setContentType).ExecuteAsync(method, url, callback)
 }
 
 // 通用GET请求，可先使用GGET绑定参数，再调用此方法
 func (c *client) Get(url string) IResponse {
-	return c.sendWithMethod(nil, http.MethodGet, url, nil, nil)
+	return c.GetWithContext(context.Background(), url)
+}
+
+// GetWithContext 携带ctx的GET请求，ctx 取消或超时时，请求随之中断
+func (c *client) GetWithContext(ctx context.Context, url string) IResponse {
+	return c.sendWithMethod(ctx, http.MethodGet, url, nil, nil)
 }
 
 // GET异步请求，使用回调函数
 func (c *client) GetAsync(url string, call func(response IResponse)) error {
-	return c.sendWithMethodCallback(nil, http.MethodGet, url, nil, nil, call)
+	return c.GetAsyncWithContext(context.Background(), url, call)
+}
+
+// GetAsyncWithContext 携带ctx的GET异步请求，使用回调函数
+func (c *client) GetAsyncWithContext(ctx context.Context, url string, call func(response IResponse)) error {
+	if call == nil {
+		return errors.New("callback function is nil")
+	}
+	return c.sendWithMethodCallback(ctx, http.MethodGet, url, nil, nil, call)
 }
 
 // GET异步请求，使用回调接口
 func (c *client) GetAsyncWithCallback(url string, call ICallBack) error {
-	return c.GetAsync(url, call.ResponseCallback)
+	return c.GetAsyncWithCallbackWithContext(context.Background(), url, call)
+}
+
+// GetAsyncWithCallbackWithContext 携带ctx的GET异步请求，使用回调接口
+func (c *client) GetAsyncWithCallbackWithContext(ctx context.Context, url string, call ICallBack) error {
+	return c.GetAsyncWithContext(ctx, url, call.ResponseCallback)
 }
 
 // post 的form请求
 func (c *client) PostForm(url string, values url.Values) IResponse {
-	var reader io.Reader
-	var ctx context.Context
+	return c.PostFormWithContext(context.Background(), url, values)
+}
 
-	ctx = nil
+// PostFormWithContext 携带ctx的post form请求
+func (c *client) PostFormWithContext(ctx context.Context, url string, values url.Values) IResponse {
+	var reader io.Reader
 
+	ctx = withDefaultContext(ctx)
 	if values != nil {
 		reader = strings.NewReader(values.Encode())
-		ctx = c.buildContext(values.Encode())
+		ctx = c.buildContext(ctx, values.Encode())
 	}
 
 	return c.sendWithMethod(ctx, http.MethodPost, url, reader, setRequestPostFrom)
@@ -207,6 +461,11 @@ func (c *client) PostForm(url string, values url.Values) IResponse {
 
 // Post form 异步请求,使用回调函数
 func (c *client) PostFormAsyn(url string, values url.Values, call func(response IResponse)) error {
+	return c.PostFormAsynWithContext(context.Background(), url, values, call)
+}
+
+// PostFormAsynWithContext 携带ctx的post form异步请求,使用回调函数
+func (c *client) PostFormAsynWithContext(ctx context.Context, url string, values url.Values, call func(response IResponse)) error {
 	if call == nil {
 		return errors.New("callback function is nil")
 	}
@@ -216,27 +475,42 @@ func (c *client) PostFormAsyn(url string, values url.Values, call func(response
 	}
 
 	reader := strings.NewReader(values.Encode())
-	ctx := c.buildContext(values.Encode())
+	ctx = c.buildContext(ctx, values.Encode())
 	return c.sendWithMethodCallback(ctx, http.MethodPost, url, reader, setRequestPostFrom, call)
 }
 
 // Post form 异步请求,使用接口回调
 func (c *client) PostFormAsynWithCallback(url string, values url.Values, call ICallBack) error {
-	return c.PostFormAsyn(url, values, call.ResponseCallback)
+	return c.PostFormAsynWithCallbackWithContext(context.Background(), url, values, call)
+}
+
+// PostFormAsynWithCallbackWithContext 携带ctx的post form异步请求,使用接口回调
+func (c *client) PostFormAsynWithCallbackWithContext(ctx context.Context, url string, values url.Values, call ICallBack) error {
+	return c.PostFormAsynWithContext(ctx, url, values, call.ResponseCallback)
 }
 
 // post 的bytes请求
 func (c *client) PostBytes(url string, value []byte, req func(request *http.Request)) IResponse {
+	return c.PostBytesWithContext(context.Background(), url, value, req)
+}
+
+// PostBytesWithContext 携带ctx的post bytes请求
+func (c *client) PostBytesWithContext(ctx context.Context, url string, value []byte, req func(request *http.Request)) IResponse {
 	if value == nil {
-		return c.logger(c.buildResponse(nil, nil, errors.New("PostBytes value is nil")))
+		return c.errorResponse(ctx, errors.New("PostBytes value is nil"))
 	}
 	reader := bytes.NewReader(value)
-	ctx := c.buildContext(string(value))
+	ctx = c.buildContext(ctx, string(value))
 	return c.sendWithMethod(ctx, http.MethodPost, url, reader, req)
 }
 
 // post 的bytes请求
 func (c *client) PostBytesAsyn(url string, value []byte, req func(request *http.Request), call func(response IResponse)) error {
+	return c.PostBytesAsynWithContext(context.Background(), url, value, req, call)
+}
+
+// PostBytesAsynWithContext 携带ctx的post bytes异步请求,使用回调函数
+func (c *client) PostBytesAsynWithContext(ctx context.Context, url string, value []byte, req func(request *http.Request), call func(response IResponse)) error {
 	if call == nil {
 		return errors.New("callback function is nil")
 	}
@@ -246,163 +520,308 @@ func (c *client) PostBytesAsyn(url string, value []byte, req func(request *http.
 	}
 
 	reader := bytes.NewReader(value)
-	ctx := c.buildContext(string(value))
+	ctx = c.buildContext(ctx, string(value))
 	return c.sendWithMethodCallback(ctx, http.MethodPost, url, reader, req, call)
 }
 
 // post 的json请求
 func (c *client) PostJson(url string, value interface{}) IResponse {
+	return c.PostJsonWithContext(context.Background(), url, value)
+}
+
+// PostJsonWithContext 携带ctx的post json请求
+func (c *client) PostJsonWithContext(ctx context.Context, url string, value interface{}) IResponse {
 	if value == nil {
-		return c.logger(c.buildResponse(nil, nil, errors.New("PostJson value is nil")))
+		return c.errorResponse(ctx, errors.New("PostJson value is nil"))
 	}
-	by, err := json.Marshal(value)
+	by, err := c.marshal(HTTP_CONTENT_TYPE_JSON, value)
 	if err != nil {
-		return c.logger(c.buildResponse(nil, nil, err))
+		return c.errorResponse(ctx, err)
 	}
-	return c.PostBytes(url, by, setRequestPostJson)
+	return c.PostBytesWithContext(ctx, url, by, setRequestPostJson)
 }
 
 // Post json 异步请求,使用回调函数
 func (c *client) PostJsonAsyn(url string, value interface{}, call func(response IResponse)) error {
+	return c.PostJsonAsynWithContext(context.Background(), url, value, call)
+}
+
+// PostJsonAsynWithContext 携带ctx的post json异步请求,使用回调函数
+func (c *client) PostJsonAsynWithContext(ctx context.Context, url string, value interface{}, call func(response IResponse)) error {
 	if call == nil {
 		return errors.New("callback function is nil")
 	}
 	if value == nil {
 		return errors.New("value is nil")
 	}
-	by, err := json.Marshal(value)
+	by, err := c.marshal(HTTP_CONTENT_TYPE_JSON, value)
 	if err != nil {
 		return errors.New("value json encode error: " + err.Error())
 	}
-	return c.PostBytesAsyn(url, by, setRequestPostJson, call)
+	return c.PostBytesAsynWithContext(ctx, url, by, setRequestPostJson, call)
 }
 
 // Post json 异步请求,使用接口回调
 func (c *client) PostJsonAsynWithCallback(url string, values interface{}, call ICallBack) error {
-	return c.PostJsonAsyn(url, values, call.ResponseCallback)
+	return c.PostJsonAsynWithCallbackWithContext(context.Background(), url, values, call)
+}
+
+// PostJsonAsynWithCallbackWithContext 携带ctx的post json异步请求,使用接口回调
+func (c *client) PostJsonAsynWithCallbackWithContext(ctx context.Context, url string, values interface{}, call ICallBack) error {
+	return c.PostJsonAsynWithContext(ctx, url, values, call.ResponseCallback)
 }
 
 // post 的xml请求
 func (c *client) PostXml(url string, value interface{}) IResponse {
+	return c.PostXmlWithContext(context.Background(), url, value)
+}
+
+// PostXmlWithContext 携带ctx的post xml请求
+func (c *client) PostXmlWithContext(ctx context.Context, url string, value interface{}) IResponse {
 	if value == nil {
-		return c.logger(c.buildResponse(nil, nil, errors.New("PostJson value is nil")))
+		return c.errorResponse(ctx, errors.New("PostJson value is nil"))
 	}
-	by, err := xml.Marshal(value)
+	by, err := c.marshal(HTTP_CONTENT_TYPE_XML, value)
 	if err != nil {
-		return c.logger(c.buildResponse(nil, nil, err))
+		return c.errorResponse(ctx, err)
 	}
-	return c.PostBytes(url, by, setRequestPostXml)
+	return c.PostBytesWithContext(ctx, url, by, setRequestPostXml)
 }
 
 // Post xml 异步请求,使用回调函数
 func (c *client) PostXmlAsyn(url string, value interface{}, call func(response IResponse)) error {
+	return c.PostXmlAsynWithContext(context.Background(), url, value, call)
+}
+
+// PostXmlAsynWithContext 携带ctx的post xml异步请求,使用回调函数
+func (c *client) PostXmlAsynWithContext(ctx context.Context, url string, value interface{}, call func(response IResponse)) error {
 	if call == nil {
 		return errors.New("callback function is nil")
 	}
 	if value == nil {
 		return errors.New("value is nil")
 	}
-	by, err := json.Marshal(value)
+	by, err := c.marshal(HTTP_CONTENT_TYPE_XML, value)
 	if err != nil {
-		return errors.New("value json encode error: " + err.Error())
+		return errors.New("value xml encode error: " + err.Error())
 	}
-	return c.PostBytesAsyn(url, by, setRequestPostXml, call)
+	return c.PostBytesAsynWithContext(ctx, url, by, setRequestPostXml, call)
 }
 
 // Post xml 异步请求,使用接口回调
 func (c *client) PostXmlAsynWithCallback(url string, values interface{}, call ICallBack) error {
-	return c.PostXmlAsyn(url, values, call.ResponseCallback)
+	return c.PostXmlAsynWithCallbackWithContext(context.Background(), url, values, call)
+}
+
+// PostXmlAsynWithCallbackWithContext 携带ctx的post xml异步请求,使用接口回调
+func (c *client) PostXmlAsynWithCallbackWithContext(ctx context.Context, url string, values interface{}, call ICallBack) error {
+	return c.PostXmlAsynWithContext(ctx, url, values, call.ResponseCallback)
 }
 
 // post 的multipart请求
 func (c *client) PostMultipart(url string, body IMultipart) IResponse {
-	return c.sendWithMethod(nil, http.MethodPost, url, body, func(request *http.Request) {
+	return c.PostMultipartWithContext(context.Background(), url, body)
+}
+
+// PostMultipartWithContext 携带ctx的post multipart请求
+func (c *client) PostMultipartWithContext(ctx context.Context, url string, body IMultipart) IResponse {
+	return c.sendWithMethod(ctx, http.MethodPost, url, body, func(request *http.Request) {
 		request.Header.Set("Content-Type", body.ContentType())
 	})
 }
 
 // post 的multipart请求,使用回调函数
 func (c *client) PostMultipartAsyn(url string, body IMultipart, call func(response IResponse)) error {
+	return c.PostMultipartAsynWithContext(context.Background(), url, body, call)
+}
+
+// PostMultipartAsynWithContext 携带ctx的post multipart异步请求,使用回调函数
+func (c *client) PostMultipartAsynWithContext(ctx context.Context, url string, body IMultipart, call func(response IResponse)) error {
 	if call == nil {
 		return errors.New("callback function is nil")
 	}
-	return c.sendWithMethodCallback(nil, http.MethodPost, url, body, func(request *http.Request) {
+	return c.sendWithMethodCallback(ctx, http.MethodPost, url, body, func(request *http.Request) {
 		request.Header.Set("Content-Type", body.ContentType())
 	}, call)
 }
 
 // post 的multipart请求,使用接口回调
 func (c *client) PostMultipartAsynWithCallback(url string, body IMultipart, call ICallBack) error {
-	return c.PostMultipartAsyn(url, body, call.ResponseCallback)
+	return c.PostMultipartAsynWithCallbackWithContext(context.Background(), url, body, call)
+}
+
+// PostMultipartAsynWithCallbackWithContext 携带ctx的post multipart异步请求,使用接口回调
+func (c *client) PostMultipartAsynWithCallbackWithContext(ctx context.Context, url string, body IMultipart, call ICallBack) error {
+	return c.PostMultipartAsynWithContext(ctx, url, body, call.ResponseCallback)
+}
+
+// Do 以指定的method发起请求，body 为 nil 时不携带body
+func (c *client) Do(method, url string, body Body) IResponse {
+	return c.DoWithContext(context.Background(), method, url, body)
+}
+
+// DoWithContext 携带ctx，以指定的method发起请求
+func (c *client) DoWithContext(ctx context.Context, method, url string, body Body) IResponse {
+	return c.legacyRequest(ctx, nil, nil).SetBodyEncoder(body).Execute(method, url)
+}
+
+// DoAsync 以指定的method发起异步请求,使用回调函数
+func (c *client) DoAsync(method, url string, body Body, call func(response IResponse)) error {
+	return c.DoAsyncWithContext(context.Background(), method, url, body, call)
+}
+
+// DoAsyncWithContext 携带ctx，以指定的method发起异步请求,使用回调函数
+func (c *client) DoAsyncWithContext(ctx context.Context, method, url string, body Body, call func(response IResponse)) error {
+	return c.legacyRequest(ctx, nil, nil).SetBodyEncoder(body).ExecuteAsync(method, url, call)
 }
 
+// DoAsyncWithCallback 以指定的method发起异步请求,使用接口回调
+func (c *client) DoAsyncWithCallback(method, url string, body Body, call ICallBack) error {
+	return c.DoAsyncWithCallbackWithContext(context.Background(), method, url, body, call)
+}
+
+// DoAsyncWithCallbackWithContext 携带ctx，以指定的method发起异步请求,使用接口回调
+func (c *client) DoAsyncWithCallbackWithContext(ctx context.Context, method, url string, body Body, call ICallBack) error {
+	return c.DoAsyncWithContext(ctx, method, url, body, call.ResponseCallback)
+}
+
+// Put 发起PUT请求，如 client.Put(url, ghttp.JsonBody(value))
+func (c *client) Put(url string, body Body) IResponse {
+	return c.Do(http.MethodPut, url, body)
+}
+
+// PutWithContext 携带ctx的PUT请求
+func (c *client) PutWithContext(ctx context.Context, url string, body Body) IResponse {
+	return c.DoWithContext(ctx, http.MethodPut, url, body)
+}
+
+// Patch 发起PATCH请求
+func (c *client) Patch(url string, body Body) IResponse {
+	return c.Do(http.MethodPatch, url, body)
+}
+
+// PatchWithContext 携带ctx的PATCH请求
+func (c *client) PatchWithContext(ctx context.Context, url string, body Body) IResponse {
+	return c.DoWithContext(ctx, http.MethodPatch, url, body)
+}
+
+// Delete 发起DELETE请求，body 为 nil 时不携带body
+func (c *client) Delete(url string, body Body) IResponse {
+	return c.Do(http.MethodDelete, url, body)
+}
+
+// DeleteWithContext 携带ctx的DELETE请求
+func (c *client) DeleteWithContext(ctx context.Context, url string, body Body) IResponse {
+	return c.DoWithContext(ctx, http.MethodDelete, url, body)
+}
+
+// Head 发起HEAD请求
+func (c *client) Head(url string) IResponse {
+	return c.Do(http.MethodHead, url, nil)
+}
+
+// HeadWithContext 携带ctx的HEAD请求
+func (c *client) HeadWithContext(ctx context.Context, url string) IResponse {
+	return c.DoWithContext(ctx, http.MethodHead, url, nil)
+}
+
+// Options 发起OPTIONS请求
+func (c *client) Options(url string) IResponse {
+	return c.Do(http.MethodOptions, url, nil)
+}
+
+// OptionsWithContext 携带ctx的OPTIONS请求
+func (c *client) OptionsWithContext(ctx context.Context, url string) IResponse {
+	return c.DoWithContext(ctx, http.MethodOptions, url, nil)
+}
+
+// ctx中内部使用的key，使用非导出类型，避免与调用方ctx中同名的字符串key冲突
+type ctxKey int
+
+const (
+	//请求开始时间，time.Time
+	ctxKeyStartTime ctxKey = iota
+
+	//第几次尝试，int
+	ctxKeyAttempt
+
+	//日志中记录的请求body，string
+	ctxKeyBody
+
+	//是否流式响应，bool
+	ctxKeyStream
+
+	//下载进度回调，*progressOption
+	ctxKeyDownloadProgress
+
+	//请求的唯一标识，string
+	ctxKeyUniqueId
+
+	//请求单独设置的是否记录请求body，bool
+	ctxKeyLogRequestBody
+
+	//请求单独设置的是否记录响应body，bool
+	ctxKeyLogResponseBody
+
+	//本次尝试是否已发出请求，由熔断中间件设置，*bool
+	ctxKeySent
+)
+
 // 设置请求上下文，用于日志记录
-func (c *client) buildContext(body string) context.Context {
-	ctx := context.Background()
-	return context.WithValue(ctx, "body", body)
+func (c *client) buildContext(ctx context.Context, body string) context.Context {
+	return context.WithValue(withDefaultContext(ctx), ctxKeyBody, body)
 }
 
-// 设置请求时间到ctx
-func (c *client) buildStartTime(ctx context.Context) context.Context {
+// 调用方未传入ctx时，使用 context.Background() 兜底
+func withDefaultContext(ctx context.Context) context.Context {
 	if ctx == nil {
-		ctx = context.Background()
+		return context.Background()
 	}
+	return ctx
+}
+
+// 设置请求时间到ctx
+func (c *client) buildStartTime(ctx context.Context) context.Context {
+	ctx = withDefaultContext(ctx)
 	startTime := time.Now()
-	return context.WithValue(ctx, "startTime", startTime)
+	return context.WithValue(ctx, ctxKeyStartTime, startTime)
 }
 
 // log记录
-func (c *client) logger(ctx context.Context, resp IResponse) IResponse {
+func (c *client) logger(ctx context.Context, request *http.Request, resp IResponse) IResponse {
 
-	//请求错误处理
-	if resp.Error() != nil {
-		//远程服务异常时，不再记录日志，该异常交由调用方自行处理
+	//关闭了错误日志时，请求失败不记录日志，该异常交由调用方自行处理
+	if resp.Error() != nil && !c.logErrors {
 		return resp
 	}
 
-	if len(c.loggerFilePath) != 0 {
-		//存在文件路径，走文件路径输出
-		var filePath strings.Builder
-		filePath.WriteString(c.loggerFilePath)
-		filePath.WriteByte('/')
-		filePath.WriteString(time.Now().Format("20060102"))
-		filePath.WriteString(".log")
-
-		file, err := os.OpenFile(filePath.String(), os.O_CREATE|os.O_APPEND, os.ModePerm)
-		if err != nil {
-			log.Println("打开日志文件失败: " + err.Error())
-			return resp
-		}
+	record := c.newLogRecord(ctx, request, resp)
 
-		c.loggerWrite(file, ctx, resp)
+	//存在文件路径，走文件路径输出
+	if c.fileLogger != nil {
+		record.write(c.fileLogger)
 	}
 
 	//debug模式，同时启用控制台日志输出
-	if c.debugMode {
-		c.loggerWrite(c.loggerWriter, ctx, resp)
+	if c.consoleLogger != nil {
+		record.write(c.consoleLogger)
+	}
+
+	//自定义的日志输出
+	if c.customLogger != nil {
+		record.write(c.customLogger)
 	}
 
 	return resp
 }
 
-func (c *client) loggerWrite(loggerWriter io.Writer, ctx context.Context, resp IResponse) {
-	logger := log.New(loggerWriter, "curl   ", log.LstdFlags)
-
-	//统计请求耗时
-	startTime := ctx.Value("startTime").(time.Time)
-	costTime := time.Since(startTime).String()
-
-	header, _ := json.Marshal(resp.Request().Header)
-	if ctx == nil {
-		logger.Printf("\t%s\t%s %s %s  header:%s\tparams:%s\tresponse:%s", costTime, c.uniqueId, resp.Request().Method, resp.Request().URL, string(header), "", string(resp.Content()))
-		return
+// 日志中记录的响应内容，流式响应不读取body，只记录长度
+func logResponseContent(resp IResponse) string {
+	if !resp.Streamed() {
+		return string(resp.Content())
 	}
-
-	body := ctx.Value("body")
-	var bodyStr string
-	if body != nil {
-		bodyStr = body.(string)
+	if length := resp.ContentLength(); length >= 0 {
+		return fmt.Sprintf("<streamed %d bytes>", length)
 	}
-
-	logger.Printf("\t%s\t%s %s %s  header:%s\tparams:%s\tresponse:%s", costTime, c.uniqueId, resp.Request().Method, resp.Request().URL, string(header), bodyStr, string(resp.Content()))
+	return "<streamed>"
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -17,11 +18,19 @@ import (
 // NewClientBuilder 初始化
 func NewClientBuilder() *ClientBuilder {
 	return &ClientBuilder{
-		skipVerify:    true,
-		openJar:       false,
-		buildResponse: DefaultBuildResponse,
-		loggerWriter:  os.Stdout,
-		debugMode:     false,
+		skipVerify:      true,
+		openJar:         false,
+		buildResponse:   DefaultBuildResponse,
+		loggerWriter:    os.Stdout,
+		debugMode:       false,
+		logLevel:        slog.LevelInfo,
+		logLevels:       DefaultLogLevels(),
+		codecs:          defaultCodecs(),
+		redactor:        newRedactor(),
+		logErrors:       true,
+		logBodyLimit:    defaultLogBodyLimit,
+		logRequestBody:  true,
+		logResponseBody: true,
 	}
 }
 
@@ -65,11 +74,59 @@ type ClientBuilder struct {
 
 	//日志写入路径, 默认不存在，走stdout
 	logFilePath string
+	//日志文件的切分及清理设置，默认只按天切分
+	logFileOptions LogFileOptions
 	//日志写入io, 默认stdout
 	loggerWriter io.Writer
 
 	// 调试开关
 	debugMode bool
+
+	//内置日志输出（文件、控制台）的最低级别，默认Info，设为Debug时记录请求头及body
+	logLevel slog.Level
+
+	//按请求结果使用的日志级别，默认见 DefaultLogLevels
+	logLevels LogLevels
+
+	//自定义的日志输出，与文件、控制台输出同时生效
+	logger Logger
+
+	//日志脱敏规则，默认脱敏 DefaultRedactHeaders 中的请求头
+	redactor *redactor
+
+	//请求失败（超时、连接失败等）时是否记录日志，默认记录
+	logErrors bool
+
+	//日志中body的最大长度，超出部分截断，默认4096字节
+	logBodyLimit int
+
+	//日志中是否记录请求、响应body，默认记录
+	logRequestBody  bool
+	logResponseBody bool
+
+	//重试策略，默认不重试
+	retry *retryPolicy
+
+	//请求中间件，按添加顺序由外到内执行
+	middlewares []Middleware
+
+	//判断状态码是否视为错误，默认只有传输错误才视为错误
+	errorOnStatus func(statusCode int) bool
+
+	//按 Content-Type 注册的编解码器，默认包含json、xml、form、msgpack、protobuf
+	codecs codecRegistry
+
+	//请求的基础地址，相对地址的请求拼接在其后
+	baseURL string
+
+	//限流设置，默认不限流
+	rateLimit *RateLimitOptions
+
+	//熔断设置，默认不熔断
+	circuitBreaker *CircuitBreakerOptions
+
+	//异步请求执行器设置，默认不限制并发
+	executor ExecutorOptions
 }
 
 func (builder *ClientBuilder) SetTimeOut(t time.Duration) *ClientBuilder {
@@ -82,6 +139,13 @@ func (builder *ClientBuilder) SetProxyUrl(u string) *ClientBuilder {
 	return builder
 }
 
+// SetBaseURL 设置基础地址，Get、Post* 等方法传入相对地址时拼接在其后
+// 如 SetBaseURL("https://api.example.com/v1") 后，client.Get("/users") 请求 https://api.example.com/v1/users
+func (builder *ClientBuilder) SetBaseURL(u string) *ClientBuilder {
+	builder.baseURL = u
+	return builder
+}
+
 func (builder *ClientBuilder) SetTls(tlsPath []*TlsPath) *ClientBuilder {
 	builder.tlsPath = tlsPath
 	return builder
@@ -137,12 +201,166 @@ func (builder *ClientBuilder) SetLogFilePath(path string) *ClientBuilder {
 	return builder
 }
 
+// SetLogFileOptions 设置日志文件按大小切分、保留的文件数及时长、是否压缩，需同时通过 SetLogFilePath 设置日志目录
+// 多个 client 使用同一日志目录时共用一个日志文件，以最先构造的 client 的设置为准
+func (builder *ClientBuilder) SetLogFileOptions(options LogFileOptions) *ClientBuilder {
+	builder.logFileOptions = options
+	return builder
+}
+
 // 关闭控制台日志输出
 func (builder *ClientBuilder) Debug() *ClientBuilder {
 	builder.debugMode = true
 	return builder
 }
 
+// SetLogger 设置自定义的日志输出，可直接使用 *slog.Logger，如 SetLogger(slog.Default())
+func (builder *ClientBuilder) SetLogger(logger Logger) *ClientBuilder {
+	builder.logger = logger
+	return builder
+}
+
+// SetLogLevel 设置内置日志输出（文件、控制台）的最低级别，默认Info；设为 slog.LevelDebug 时记录请求头及body
+func (builder *ClientBuilder) SetLogLevel(level slog.Level) *ClientBuilder {
+	builder.logLevel = level
+	return builder
+}
+
+// SetLogLevels 设置按请求结果使用的日志级别，可在 DefaultLogLevels() 的基础上修改
+func (builder *ClientBuilder) SetLogLevels(levels LogLevels) *ClientBuilder {
+	builder.logLevels = levels
+	return builder
+}
+
+// LogErrors 设置请求失败时是否记录日志，默认记录，日志包含错误信息及分类（error_kind），级别为 LogLevels.Failure
+func (builder *ClientBuilder) LogErrors(enabled bool) *ClientBuilder {
+	builder.logErrors = enabled
+	return builder
+}
+
+// SetLogBodyLimit 设置日志中body的最大长度，超出部分截断并标记，<=0 时不限制，默认4096字节
+func (builder *ClientBuilder) SetLogBodyLimit(limit int) *ClientBuilder {
+	builder.logBodyLimit = limit
+	return builder
+}
+
+// LogRequestBody 设置日志中是否记录请求body，默认记录，可通过 Request.SetLogRequestBody 按请求覆盖
+func (builder *ClientBuilder) LogRequestBody(enabled bool) *ClientBuilder {
+	builder.logRequestBody = enabled
+	return builder
+}
+
+// LogResponseBody 设置日志中是否记录响应body，默认记录，可通过 Request.SetLogResponseBody 按请求覆盖
+func (builder *ClientBuilder) LogResponseBody(enabled bool) *ClientBuilder {
+	builder.logResponseBody = enabled
+	return builder
+}
+
+// RedactHeaders 追加日志中需要脱敏的请求头，不区分大小写，默认已包含 DefaultRedactHeaders
+func (builder *ClientBuilder) RedactHeaders(names ...string) *ClientBuilder {
+	builder.redactor.addHeaders(names...)
+	return builder
+}
+
+// RedactJsonFields 设置日志中需要脱敏的json字段，作用于json格式的请求及响应body
+// 路径以 . 分隔，如 user.token，从根节点开始匹配，* 匹配任意字段名，数组的元素逐个匹配；不含 . 的路径如 password 匹配任意层级的同名字段
+func (builder *ClientBuilder) RedactJsonFields(paths ...string) *ClientBuilder {
+	builder.redactor.addJsonPaths(paths...)
+	return builder
+}
+
+// RedactFormKeys 设置日志中需要脱敏的form表单key，不区分大小写，作用于form格式的请求及响应body
+func (builder *ClientBuilder) RedactFormKeys(keys ...string) *ClientBuilder {
+	builder.redactor.formKeys = append(builder.redactor.formKeys, keys...)
+	return builder
+}
+
+// RedactQueryParams 设置日志中url需要脱敏的query参数，不区分大小写，url中的密码始终脱敏
+func (builder *ClientBuilder) RedactQueryParams(params ...string) *ClientBuilder {
+	builder.redactor.queryParams = append(builder.redactor.queryParams, params...)
+	return builder
+}
+
+// SetRedactor 设置自定义脱敏，在内置脱敏之后对日志的每个属性调用
+func (builder *ClientBuilder) SetRedactor(redactor Redactor) *ClientBuilder {
+	builder.redactor.custom = redactor
+	return builder
+}
+
+// SetRetry 设置失败重试，count 为最大重试次数（不含首次请求），backoff 为 nil 时使用默认的指数退避
+func (builder *ClientBuilder) SetRetry(count int, backoff Backoff) *ClientBuilder {
+	if builder.retry == nil {
+		builder.retry = &retryPolicy{}
+	}
+	builder.retry.count = count
+	builder.retry.backoff = backoff
+	return builder
+}
+
+// SetRetryCondition 设置重试条件，默认使用 DefaultRetryCondition
+func (builder *ClientBuilder) SetRetryCondition(condition RetryCondition) *ClientBuilder {
+	if builder.retry == nil {
+		builder.retry = &retryPolicy{}
+	}
+	builder.retry.condition = condition
+	return builder
+}
+
+// SetRetryMaxWait 设置单次重试前的最长等待时间，默认1分钟
+// 响应的 Retry-After 超出该时间时不再重试，直接返回该响应；退避时间超出时按该时间等待
+func (builder *ClientBuilder) SetRetryMaxWait(maxWait time.Duration) *ClientBuilder {
+	if builder.retry == nil {
+		builder.retry = &retryPolicy{}
+	}
+	builder.retry.maxWait = maxWait
+	return builder
+}
+
+// ErrorOnStatus 设置视为错误的状态码，命中时 IResponse.Error() 返回 *HTTPError
+// 如 ErrorOnStatus(func(code int) bool { return code >= 400 })
+func (builder *ClientBuilder) ErrorOnStatus(isError func(statusCode int) bool) *ClientBuilder {
+	builder.errorOnStatus = isError
+	return builder
+}
+
+// RegisterCodec 注册编解码器，按 codec.ContentType() 及 contentTypes 注册，同类型的编解码器会被覆盖
+// 如使用更快的json库：RegisterCodec(myJsonCodec{})，其 ContentType() 返回 application/json
+func (builder *ClientBuilder) RegisterCodec(codec Codec, contentTypes ...string) *ClientBuilder {
+	builder.codecs.register(codec)
+	for _, contentType := range contentTypes {
+		builder.codecs[mediaType(contentType)] = codec
+	}
+	return builder
+}
+
+// SetRateLimit 设置令牌桶限流，可按host或自定义的key分组
+// 如每个host每秒10个请求：SetRateLimit(&ghttp.RateLimitOptions{Rate: 10, KeyFunc: ghttp.ByHost})
+func (builder *ClientBuilder) SetRateLimit(options *RateLimitOptions) *ClientBuilder {
+	builder.rateLimit = options
+	return builder
+}
+
+// SetCircuitBreaker 设置熔断器，可按host或自定义的key分组，熔断器打开时请求直接返回 ErrCircuitOpen
+// 如按host熔断：SetCircuitBreaker(&ghttp.CircuitBreakerOptions{KeyFunc: ghttp.ByHost})
+func (builder *ClientBuilder) SetCircuitBreaker(options *CircuitBreakerOptions) *ClientBuilder {
+	builder.circuitBreaker = options
+	return builder
+}
+
+// SetExecutor 设置异步请求执行器，限制 *Async* 系列方法同时执行的请求数及等待队列的长度
+// 如最多100个并发、1000个排队，队列满时拒绝：SetExecutor(ghttp.ExecutorOptions{MaxConcurrency: 100, QueueSize: 1000, FullPolicy: ghttp.QueueReject})
+func (builder *ClientBuilder) SetExecutor(options ExecutorOptions) *ClientBuilder {
+	builder.executor = options
+	return builder
+}
+
+// Use 添加请求中间件，先添加的中间件位于外层
+// 中间件位于内置日志中间件之内、内置熔断及限流中间件之外
+func (builder *ClientBuilder) Use(middlewares ...Middleware) *ClientBuilder {
+	builder.middlewares = append(builder.middlewares, middlewares...)
+	return builder
+}
+
 // Build 构造 client
 func (builder *ClientBuilder) Build() (*client, error) {
 	var (
@@ -162,6 +380,17 @@ func (builder *ClientBuilder) Build() (*client, error) {
 		}
 	}
 
+	var baseURL *url.URL
+	if builder.baseURL != "" {
+		baseURL, err = url.Parse(builder.baseURL)
+		if err != nil {
+			return nil, err
+		}
+		if !baseURL.IsAbs() {
+			return nil, fmt.Errorf("base url %q is not absolute", builder.baseURL)
+		}
+	}
+
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: builder.skipVerify,
 	}
@@ -202,13 +431,47 @@ func (builder *ClientBuilder) Build() (*client, error) {
 			Timeout:       builder.timeOut,
 			CheckRedirect: builder.checkRedirect,
 		},
-		header:         builder.header,
-		cookies:        builder.cookie,
-		buildResponse:  builder.buildResponse,
-		loggerWriter:   builder.loggerWriter,
-		debugMode:      builder.debugMode,
-		loggerFilePath: builder.logFilePath,
+		header:          copyHeader(builder.header),
+		cookies:         copyCookies(builder.cookie),
+		buildResponse:   builder.buildResponse,
+		loggerWriter:    builder.loggerWriter,
+		debugMode:       builder.debugMode,
+		loggerFilePath:  builder.logFilePath,
+		logLevel:        builder.logLevel,
+		logLevels:       builder.logLevels,
+		customLogger:    builder.logger,
+		redactor:        builder.redactor.clone(),
+		logErrors:       builder.logErrors,
+		logBodyLimit:    builder.logBodyLimit,
+		logRequestBody:  builder.logRequestBody,
+		logResponseBody: builder.logResponseBody,
+		errorOnStatus:   builder.errorOnStatus,
+		codecs:          builder.codecs.clone(),
+		baseURL:         baseURL,
+		executor:        newExecutor(builder.executor),
+	}
+
+	if builder.logFilePath != "" {
+		c.logFile = openLogFile(builder.logFilePath, builder.logFileOptions)
+		c.fileLogger = newTextLogger(c.logFile, c.logLevel)
+	}
+	if builder.debugMode && builder.loggerWriter != nil {
+		c.consoleLogger = newTextLogger(builder.loggerWriter, c.logLevel)
+	}
+	if builder.retry != nil {
+		retry := *builder.retry
+		c.retry = &retry
+	}
+	if builder.rateLimit != nil {
+		if builder.rateLimit.Rate <= 0 {
+			return nil, errors.New("rate limit must be greater than 0")
+		}
+		c.rateLimiter = newRateLimiter(*builder.rateLimit)
+	}
+	if builder.circuitBreaker != nil {
+		c.circuitBreakers = newCircuitBreakers(*builder.circuitBreaker)
 	}
+	c.handler = c.buildHandler(builder.middlewares)
 
 	if builder.openJar {
 		jar, err := cookiejar.New(builder.jarOptions)
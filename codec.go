@@ -0,0 +1,270 @@
+package ghttp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 请求body及响应内容的编解码器
+// 通过 ClientBuilder.RegisterCodec 按 Content-Type 注册，请求编码与响应解析使用同一个 Codec
+type Codec interface {
+	// Marshal 将v编码为请求body
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal 将响应内容解析到v
+	Unmarshal(data []byte, v interface{}) error
+
+	// ContentType 编码后的 Content-Type
+	ContentType() string
+}
+
+// JsonCodec 基于 encoding/json 的编解码器
+type JsonCodec struct{}
+
+func (JsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (JsonCodec) ContentType() string {
+	return HTTP_CONTENT_TYPE_JSON
+}
+
+// XmlCodec 基于 encoding/xml 的编解码器
+type XmlCodec struct{}
+
+func (XmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (XmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+func (XmlCodec) ContentType() string {
+	return HTTP_CONTENT_TYPE_XML
+}
+
+// MsgpackCodec 基于 github.com/vmihailenco/msgpack 的编解码器
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func (MsgpackCodec) ContentType() string {
+	return HTTP_CONTENT_TYPE_MSGPACK
+}
+
+// ProtobufCodec 基于 google.golang.org/protobuf 的编解码器，v 需要实现 proto.Message
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("ghttp: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(message)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("ghttp: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, message)
+}
+
+func (ProtobufCodec) ContentType() string {
+	return HTTP_CONTENT_TYPE_PROTOBUF
+}
+
+// FormCodec form表单的编解码器
+// 支持 url.Values、map[string]string、map[string][]string 及带 `form` tag 的struct，struct的编码规则见 ValuesEncoder
+type FormCodec struct{}
+
+func (FormCodec) Marshal(v interface{}) ([]byte, error) {
+	values, err := formEncoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (FormCodec) Unmarshal(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	switch target := v.(type) {
+	case *url.Values:
+		*target = values
+	case *map[string][]string:
+		*target = values
+	case *map[string]string:
+		*target = make(map[string]string, len(values))
+		for k := range values {
+			(*target)[k] = values.Get(k)
+		}
+	default:
+		return setFormStruct(values, v)
+	}
+	return nil
+}
+
+func (FormCodec) ContentType() string {
+	return HTTP_CONTENT_TYPE_FROM_DATA
+}
+
+// 将 url.Values 写入带 `form` tag 的struct，支持字符串、数值、布尔及其切片
+func setFormStruct(values url.Values, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ghttp: form codec unsupported type %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, ok := formFieldName(rt.Field(i))
+		if !ok || len(values[name]) == 0 {
+			continue
+		}
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(fv.Type(), len(values[name]), len(values[name]))
+			for j, s := range values[name] {
+				if err := setFormValue(slice.Index(j), s); err != nil {
+					return err
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+		if err := setFormValue(fv, values.Get(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setFormValue(fv reflect.Value, s string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("ghttp: form codec unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// 获取字段的 `form` tag，未设置时使用字段名，`form:"-"` 及未导出字段忽略
+func formFieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	name := strings.Split(field.Tag.Get("form"), ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// 按 Content-Type 的媒体类型注册的编解码器
+type codecRegistry map[string]Codec
+
+// 内置的编解码器
+func defaultCodecs() codecRegistry {
+	codecs := make(codecRegistry)
+	for _, codec := range []Codec{JsonCodec{}, XmlCodec{}, FormCodec{}, MsgpackCodec{}, ProtobufCodec{}} {
+		codecs.register(codec)
+	}
+	codecs["text/xml"] = XmlCodec{}
+	codecs["application/x-msgpack"] = MsgpackCodec{}
+	codecs["application/protobuf"] = ProtobufCodec{}
+	return codecs
+}
+
+func (r codecRegistry) register(codec Codec) {
+	r[mediaType(codec.ContentType())] = codec
+}
+
+func (r codecRegistry) clone() codecRegistry {
+	codecs := make(codecRegistry, len(r))
+	for k, v := range r {
+		codecs[k] = v
+	}
+	return codecs
+}
+
+// 根据 Content-Type 查找编解码器，未注册时 +json、+xml 结尾的类型使用json、xml的编解码器
+func (r codecRegistry) lookup(contentType string) (Codec, bool) {
+	if r == nil {
+		r = builtinCodecs
+	}
+	media := mediaType(contentType)
+	if codec, ok := r[media]; ok {
+		return codec, true
+	}
+	switch {
+	case strings.HasSuffix(media, "+json"):
+		return r.lookup(HTTP_CONTENT_TYPE_JSON)
+	case strings.HasSuffix(media, "+xml"):
+		return r.lookup(HTTP_CONTENT_TYPE_XML)
+	}
+	return nil, false
+}
+
+// 未通过 client 构造的响应使用的编解码器
+var builtinCodecs = defaultCodecs()
+
+// 获取 Content-Type 中的媒体类型，如 application/json; charset=utf-8 => application/json
+func mediaType(contentType string) string {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	return media
+}
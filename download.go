@@ -0,0 +1,317 @@
+package ghttp
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChecksumAlgorithm 下载文件的校验算法
+type ChecksumAlgorithm string
+
+// ChecksumAlgorithm 的枚举
+const (
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+	ChecksumMD5    ChecksumAlgorithm = "md5"
+)
+
+// 下载中的临时文件后缀，及记录 ETag/Last-Modified 的文件后缀
+const (
+	downloadTempSuffix = ".download"
+	downloadMetaSuffix = ".meta"
+)
+
+// DownloadOptions 下载选项，均为可选
+type DownloadOptions struct {
+	//是否断点续传，开启时若存在未完成的临时文件，以 Range/If-Range 请求剩余部分
+	Resume bool
+
+	//下载进度回调，current 为已下载的字节数（含续传前已下载的部分），total 未知时为 -1
+	Progress ProgressFunc
+
+	//两次进度回调的最小间隔，<=0 时使用默认的100ms
+	ProgressInterval time.Duration
+
+	//校验算法，为空时不校验
+	Algorithm ChecksumAlgorithm
+
+	//期望的校验值（hex），为空时尝试使用响应头 Content-MD5、Digest 中对应算法的值
+	Checksum string
+}
+
+// Download 下载文件到 destPath
+// 先写入 destPath.download 临时文件，校验通过后再原子重命名为 destPath，响应内容不经过内存缓存
+func (c *client) Download(ctx context.Context, url, destPath string, opts *DownloadOptions) error {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	tempPath := destPath + downloadTempSuffix
+	metaPath := tempPath + downloadMetaSuffix
+
+	var offset int64
+	var validator, savedChecksum string
+	if opts.Resume {
+		if info, err := os.Stat(tempPath); err == nil {
+			offset = info.Size()
+		}
+		if meta, err := os.ReadFile(metaPath); err == nil {
+			validator, savedChecksum = parseDownloadMeta(string(meta), opts.Algorithm)
+		}
+	}
+	//没有校验值时无法确认临时文件仍是同一份内容，从头下载
+	if validator == "" {
+		offset = 0
+	}
+	//需要校验但没有完整内容的校验值时，续传后无法校验，从头下载
+	if opts.Algorithm != "" && opts.Checksum == "" && savedChecksum == "" {
+		offset = 0
+	}
+
+	r := c.R().SetContext(ctx).SetStream()
+	if opts.Progress != nil {
+		//offset 在收到响应后可能被重置为0，回调时再读取
+		r.SetDownloadProgress(func(current, total int64) {
+			if total >= 0 {
+				total += offset
+			}
+			opts.Progress(offset+current, total)
+		}, opts.ProgressInterval)
+	}
+	if offset > 0 {
+		r.SetHeader("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		r.SetHeader("If-Range", validator)
+	}
+	resp := r.Get(url)
+	if err := resp.Error(); err != nil && !errors.As(err, new(*HTTPError)) {
+		return err
+	}
+	defer closeResponse(resp)
+
+	//续传时剩余内容的长度，-1 表示不校验长度
+	remaining := int64(-1)
+	switch resp.StatusCode() {
+	case http.StatusPartialContent:
+		//服务端接受续传，返回的范围须从 offset 开始直到文件末尾，否则拼接后的文件不完整
+		start, end, size, ok := parseContentRange(resp.Header())
+		if !ok || start != offset || size < 0 || end+1 != size {
+			return fmt.Errorf("ghttp: unexpected Content-Range %q when resuming from %d", resp.Header().Get("Content-Range"), offset)
+		}
+		remaining = end + 1 - start
+	case http.StatusRequestedRangeNotSatisfiable:
+		//临时文件已经是完整的文件
+		if offset == 0 || contentRangeSize(resp.Header()) != offset {
+			return newHTTPError(resp)
+		}
+		return finishDownload(tempPath, metaPath, destPath, opts, savedChecksum)
+	default:
+		if resp.StatusCode() < 200 || resp.StatusCode() > 299 {
+			return newHTTPError(resp)
+		}
+		//服务端返回完整内容，从头写入
+		offset = 0
+	}
+	if offset == 0 {
+		//记录完整内容的校验值，续传时使用
+		savedChecksum = ""
+		if opts.Algorithm != "" {
+			savedChecksum = headerChecksum(resp.Header(), opts.Algorithm)
+		}
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(tempPath, flag, 0644)
+	if err != nil {
+		return err
+	}
+
+	if v := responseValidator(resp.Header()); v != "" {
+		_ = os.WriteFile(metaPath, []byte(formatDownloadMeta(v, opts.Algorithm, savedChecksum)), 0644)
+	} else {
+		_ = os.Remove(metaPath)
+	}
+
+	written, err := io.Copy(file, resp.Body())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if remaining >= 0 && written != remaining {
+		return fmt.Errorf("ghttp: incomplete download, expected %d bytes, got %d", remaining, written)
+	}
+
+	return finishDownload(tempPath, metaPath, destPath, opts, savedChecksum)
+}
+
+// 校验临时文件，通过后重命名为目标文件
+// headerSum 为首次请求时响应头中完整内容的校验值，续传时响应头只对应部分内容，不能用于校验
+func finishDownload(tempPath, metaPath, destPath string, opts *DownloadOptions, headerSum string) error {
+	if opts.Algorithm != "" {
+		expected := opts.Checksum
+		if expected == "" {
+			expected = headerSum
+		}
+		if expected == "" {
+			//无法校验的临时文件不再保留，下次从头下载
+			_ = os.Remove(tempPath)
+			_ = os.Remove(metaPath)
+			return fmt.Errorf("ghttp: no %s checksum to verify", opts.Algorithm)
+		}
+		actual, err := fileChecksum(tempPath, opts.Algorithm)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(actual, expected) {
+			_ = os.Remove(tempPath)
+			_ = os.Remove(metaPath)
+			return fmt.Errorf("ghttp: %s checksum mismatch, expected %s, got %s", opts.Algorithm, expected, actual)
+		}
+	}
+
+	if err := os.Rename(tempPath, destPath); err != nil {
+		return err
+	}
+	_ = os.Remove(metaPath)
+	return nil
+}
+
+// .meta 文件的内容，第一行为 If-Range 使用的校验值，第二行为 算法:完整内容的校验值
+func formatDownloadMeta(validator string, algorithm ChecksumAlgorithm, checksum string) string {
+	if algorithm == "" || checksum == "" {
+		return validator
+	}
+	return validator + "\n" + string(algorithm) + ":" + checksum
+}
+
+// 解析 .meta 文件，返回 If-Range 使用的校验值及对应算法的完整内容校验值
+func parseDownloadMeta(meta string, algorithm ChecksumAlgorithm) (validator, checksum string) {
+	validator, rest, _ := strings.Cut(meta, "\n")
+	if name, sum, ok := strings.Cut(rest, ":"); ok && algorithm != "" && ChecksumAlgorithm(name) == algorithm {
+		checksum = sum
+	}
+	return validator, checksum
+}
+
+// 计算文件的校验值（hex）
+func fileChecksum(path string, algorithm ChecksumAlgorithm) (string, error) {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newChecksumHash(algorithm ChecksumAlgorithm) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	case ChecksumMD5:
+		return md5.New(), nil
+	}
+	return nil, fmt.Errorf("ghttp: unsupported checksum algorithm %q", algorithm)
+}
+
+// 从响应头 Content-MD5、Digest 中获取对应算法的校验值，转换为hex
+func headerChecksum(header http.Header, algorithm ChecksumAlgorithm) string {
+	if algorithm == ChecksumMD5 {
+		if sum := decodeBase64Hex(header.Get("Content-MD5")); sum != "" {
+			return sum
+		}
+	}
+
+	digestName := map[ChecksumAlgorithm]string{ChecksumSHA256: "sha-256", ChecksumMD5: "md5"}[algorithm]
+	for _, digest := range strings.Split(header.Get("Digest"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(digest), "=")
+		if ok && strings.EqualFold(name, digestName) {
+			return decodeBase64Hex(value)
+		}
+	}
+	return ""
+}
+
+func decodeBase64Hex(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(sum)
+}
+
+// 用于 If-Range 的校验值，优先使用强 ETag
+func responseValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// 解析 206 响应的 Content-Range，如 bytes 100-199/200，总长度未知（*）时 size 为 -1
+func parseContentRange(header http.Header) (start, end, size int64, ok bool) {
+	unit, spec, found := strings.Cut(header.Get("Content-Range"), " ")
+	if !found || unit != "bytes" {
+		return 0, 0, 0, false
+	}
+	rng, total, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+	first, last, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	end, err = strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, 0, false
+	}
+	size = -1
+	if total != "*" {
+		if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+			return 0, 0, 0, false
+		}
+	}
+	return start, end, size, true
+}
+
+// 解析 416 响应的 Content-Range: bytes */size
+func contentRangeSize(header http.Header) int64 {
+	contentRange := header.Get("Content-Range")
+	i := strings.LastIndexByte(contentRange, '/')
+	if i < 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
@@ -0,0 +1,156 @@
+package ghttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// 传输层错误的分类，可通过 errors.Is 判断，如 errors.Is(resp.Error(), ghttp.ErrTimeout)
+var (
+	// ErrTimeout 请求超时，包括client超时及ctx超时
+	ErrTimeout = errors.New("ghttp: request timeout")
+
+	// ErrDNS 域名解析失败
+	ErrDNS = errors.New("ghttp: dns lookup failed")
+
+	// ErrTLS TLS握手或证书校验失败
+	ErrTLS = errors.New("ghttp: tls handshake failed")
+
+	// ErrConnectionRefused 连接被拒绝
+	ErrConnectionRefused = errors.New("ghttp: connection refused")
+)
+
+// HTTPError 开启 ClientBuilder.ErrorOnStatus 后，命中的状态码由 IResponse.Error() 返回该错误
+// 可通过 errors.As 获取，如 var httpErr *ghttp.HTTPError; errors.As(resp.Error(), &httpErr)
+type HTTPError struct {
+	//HTTP状态码
+	StatusCode int
+
+	//响应的header
+	Header http.Header
+
+	//响应内容，超过 httpErrorBodyLimit 时截断
+	Body []byte
+
+	//请求的method
+	Method string
+
+	//请求的url，已按 client 的脱敏规则脱敏
+	URL string
+}
+
+// HTTPError 中记录的响应内容的最大长度
+const httpErrorBodyLimit = 1024
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("ghttp: %s %s: unexpected status %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// 根据响应构造 HTTPError
+func newHTTPError(resp IResponse) *HTTPError {
+	e := &HTTPError{
+		StatusCode: resp.StatusCode(),
+		Header:     resp.Header(),
+	}
+	body := resp.Content()
+	if len(body) > httpErrorBodyLimit {
+		body = body[:httpErrorBodyLimit]
+	}
+	e.Body = body
+	if request := resp.Request(); request != nil {
+		e.Method = request.Method
+		if redactor := responseRedactor(resp); redactor != nil {
+			e.URL = redactor.url(request.URL)
+		} else {
+			e.URL = request.URL.Redacted()
+		}
+	}
+	return e
+}
+
+// 获取响应所属 client 的脱敏规则
+func responseRedactor(resp IResponse) *redactor {
+	for {
+		switch r := resp.(type) {
+		case *HttpResponse:
+			return r.redactor
+		case *statusErrorResponse:
+			resp = r.IResponse
+		case *cancelOnCloseResponse:
+			resp = r.IResponse
+		default:
+			return nil
+		}
+	}
+}
+
+// 命中 ErrorOnStatus 的响应，Error() 返回 HTTPError，其余方法不变
+type statusErrorResponse struct {
+	IResponse
+	err *HTTPError
+}
+
+func (r *statusErrorResponse) Error() error {
+	return r.err
+}
+
+// 已分类的传输层错误，errors.Is 可同时匹配分类及原始错误
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.kind
+}
+
+// 对传输层错误进行分类，无法分类时原样返回
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if kind := errorKind(err); kind != nil {
+		return &classifiedError{kind: kind, err: err}
+	}
+	return err
+}
+
+func errorKind(err error) error {
+	var (
+		dnsErr      *net.DNSError
+		netErr      net.Error
+		unknownAuth x509.UnknownAuthorityError
+		certInvalid x509.CertificateInvalidError
+		hostnameErr x509.HostnameError
+		systemRoots x509.SystemRootsError
+		certVerify  *tls.CertificateVerificationError
+		alertErr    tls.AlertError
+		recordErr   tls.RecordHeaderError
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrConnectionRefused
+	case errors.As(err, &certVerify), errors.As(err, &unknownAuth), errors.As(err, &certInvalid), errors.As(err, &hostnameErr),
+		errors.As(err, &systemRoots), errors.As(err, &alertErr), errors.As(err, &recordErr):
+		return ErrTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package ghttp
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 异步执行器的错误
+var (
+	//队列已满且 FullPolicy 为 QueueReject 时返回
+	ErrQueueFull = errors.New("ghttp: async queue is full")
+
+	//任务因队列已满被丢弃时，回调收到的 IResponse.Error()
+	ErrTaskDropped = errors.New("ghttp: async task dropped")
+
+	//client 调用 Shutdown 后，再发起异步请求时返回
+	ErrClientShutdown = errors.New("ghttp: client is shut down")
+)
+
+// QueueFullPolicy 异步队列已满时的处理方式
+type QueueFullPolicy int8
+
+// QueueFullPolicy 的枚举
+const (
+	//阻塞等待队列空出位置，请求的ctx结束时返回ctx的错误
+	QueueBlock QueueFullPolicy = iota
+
+	//立即返回 ErrQueueFull
+	QueueReject
+
+	//丢弃队列中最早的任务，被丢弃任务的回调在当前goroutine中以 ErrTaskDropped 调用
+	QueueDropOldest
+)
+
+// ExecutorOptions 异步请求执行器设置
+type ExecutorOptions struct {
+	//同时执行的异步请求数（含回调），<=0 时不限制
+	MaxConcurrency int
+
+	//等待执行的队列长度，达到 MaxConcurrency 后的请求进入队列
+	QueueSize int
+
+	//队列已满时的处理方式，默认阻塞
+	FullPolicy QueueFullPolicy
+}
+
+// 异步任务
+type asyncTask struct {
+	//执行请求及回调
+	run func()
+
+	//任务被丢弃时调用
+	drop func(err error)
+}
+
+// 有界的异步执行器，按需启动goroutine，空闲时不占用goroutine
+type executor struct {
+	options ExecutorOptions
+
+	mu      sync.Mutex
+	queue   []*asyncTask
+	running int
+	closed  bool
+
+	//队列空出位置时关闭并重建，用于唤醒阻塞的提交者
+	space chan struct{}
+
+	//已提交未完成的任务数，归零时关闭 idle
+	pending int
+	idle    chan struct{}
+}
+
+func newExecutor(options ExecutorOptions) *executor {
+	idle := make(chan struct{})
+	close(idle)
+	return &executor{
+		options: options,
+		space:   make(chan struct{}),
+		idle:    idle,
+	}
+}
+
+// 提交任务
+func (e *executor) submit(ctx context.Context, task *asyncTask) error {
+	e.mu.Lock()
+	for {
+		if e.closed {
+			e.mu.Unlock()
+			return ErrClientShutdown
+		}
+		if e.options.MaxConcurrency <= 0 || e.running < e.options.MaxConcurrency {
+			e.running++
+			e.addPending()
+			e.mu.Unlock()
+			go e.work(task)
+			return nil
+		}
+		if len(e.queue) < e.options.QueueSize {
+			e.queue = append(e.queue, task)
+			e.addPending()
+			e.mu.Unlock()
+			return nil
+		}
+
+		switch e.options.FullPolicy {
+		case QueueReject:
+			e.mu.Unlock()
+			return ErrQueueFull
+		case QueueDropOldest:
+			if len(e.queue) == 0 {
+				e.mu.Unlock()
+				return ErrQueueFull
+			}
+			oldest := e.queue[0]
+			e.queue = append(e.queue[1:], task)
+			e.addPending()
+			e.mu.Unlock()
+
+			oldest.drop(ErrTaskDropped)
+			e.mu.Lock()
+			e.donePending()
+			e.mu.Unlock()
+			return nil
+		default:
+			space := e.space
+			e.mu.Unlock()
+			select {
+			case <-space:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			e.mu.Lock()
+		}
+	}
+}
+
+// 执行任务，完成后继续执行队列中的任务，队列为空时退出
+func (e *executor) work(task *asyncTask) {
+	for {
+		task.run()
+
+		e.mu.Lock()
+		e.donePending()
+		if len(e.queue) == 0 {
+			e.running--
+			e.notifySpace()
+			e.mu.Unlock()
+			return
+		}
+		task = e.queue[0]
+		e.queue[0] = nil
+		e.queue = e.queue[1:]
+		e.notifySpace()
+		e.mu.Unlock()
+	}
+}
+
+func (e *executor) notifySpace() {
+	close(e.space)
+	e.space = make(chan struct{})
+}
+
+func (e *executor) addPending() {
+	if e.pending == 0 {
+		e.idle = make(chan struct{})
+	}
+	e.pending++
+}
+
+func (e *executor) donePending() {
+	e.pending--
+	if e.pending == 0 {
+		close(e.idle)
+	}
+}
+
+// 等待所有已提交的任务完成，ctx 结束时返回ctx的错误
+func (e *executor) wait(ctx context.Context) error {
+	e.mu.Lock()
+	idle := e.idle
+	e.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 停止接收新任务，并等待已提交的任务完成
+func (e *executor) shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.closed = true
+	e.notifySpace()
+	e.mu.Unlock()
+	return e.wait(ctx)
+}
@@ -0,0 +1,141 @@
+package ghttp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Future 异步请求的结果
+type Future struct {
+	client *client
+	done   chan struct{}
+	resp   IResponse
+}
+
+func newFuture(c *client) *Future {
+	return &Future{client: c, done: make(chan struct{})}
+}
+
+func (f *Future) resolve(resp IResponse) {
+	f.resp = resp
+	close(f.done)
+}
+
+// Done 请求完成时关闭，可用于 select
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await 等待请求完成并返回响应
+// ctx 先结束时返回错误为ctx错误的 IResponse，请求本身不会被取消，取消请求需使用发起请求时的ctx
+func (f *Future) Await(ctx context.Context) IResponse {
+	select {
+	case <-f.done:
+		return f.resp
+	case <-ctx.Done():
+		return f.client.errorResponse(ctx, ctx.Err())
+	}
+}
+
+// ExecuteFuture 以指定的method发起异步请求，返回 Future
+// 请求无法发起或无法提交到异步执行器时，Future 立即完成，IResponse.Error() 为对应的错误
+func (r *Request) ExecuteFuture(method, url string) *Future {
+	future := newFuture(r.client)
+	if err := r.ExecuteAsync(method, url, future.resolve); err != nil {
+		future.resolve(r.client.errorResponse(r.ctx, err))
+	}
+	return future
+}
+
+// GetFuture 发起异步GET请求，返回 Future
+func (c *client) GetFuture(url string) *Future {
+	return c.GetFutureWithContext(context.Background(), url)
+}
+
+// GetFutureWithContext 携带ctx，发起异步GET请求，返回 Future
+func (c *client) GetFutureWithContext(ctx context.Context, url string) *Future {
+	return c.legacyRequest(ctx, nil, nil).ExecuteFuture(http.MethodGet, url)
+}
+
+// PostJsonFuture 发起异步json格式的POST请求，返回 Future
+func (c *client) PostJsonFuture(url string, value interface{}) *Future {
+	return c.PostJsonFutureWithContext(context.Background(), url, value)
+}
+
+// PostJsonFutureWithContext 携带ctx，发起异步json格式的POST请求，返回 Future
+func (c *client) PostJsonFutureWithContext(ctx context.Context, url string, value interface{}) *Future {
+	return c.DoFutureWithContext(ctx, http.MethodPost, url, JsonBody(value))
+}
+
+// DoFuture 以指定的method发起异步请求，返回 Future
+func (c *client) DoFuture(method, url string, body Body) *Future {
+	return c.DoFutureWithContext(context.Background(), method, url, body)
+}
+
+// DoFutureWithContext 携带ctx，以指定的method发起异步请求，返回 Future
+func (c *client) DoFutureWithContext(ctx context.Context, method, url string, body Body) *Future {
+	return c.legacyRequest(ctx, nil, nil).SetBodyEncoder(body).ExecuteFuture(method, url)
+}
+
+// BatchCall 批量请求中的单个请求，需使用传入的ctx发起请求，以便快速失败时取消
+// 如 func(ctx context.Context) ghttp.IResponse { return client.R().SetContext(ctx).Get(url) }
+type BatchCall func(ctx context.Context) IResponse
+
+// BatchOptions 批量请求设置
+type BatchOptions struct {
+	//同时执行的请求数，<=0 时全部同时执行
+	Concurrency int
+
+	//有请求失败时取消其余请求，未开始的请求不再发起，其响应的错误为 context.Canceled
+	FailFast bool
+
+	//判断响应是否失败，默认 IResponse.Error() 不为nil时视为失败
+	IsFailure func(resp IResponse) bool
+}
+
+// Batch 以有限的并发执行一组请求，等待全部完成后按 calls 的顺序返回响应
+// 请求在当前goroutine派生的goroutine中同步执行，不占用异步执行器
+func (c *client) Batch(ctx context.Context, calls []BatchCall, opts *BatchOptions) []IResponse {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	isFailure := opts.IsFailure
+	if isFailure == nil {
+		isFailure = func(resp IResponse) bool { return resp.Error() != nil }
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 || concurrency > len(calls) {
+		concurrency = len(calls)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]IResponse, len(calls))
+	next := int64(-1)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(calls) {
+					return
+				}
+				if err := ctx.Err(); err != nil {
+					results[i] = c.errorResponse(ctx, err)
+					continue
+				}
+				results[i] = calls[i](ctx)
+				if opts.FailFast && isFailure(results[i]) {
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
@@ -1,3 +1,10 @@
 module github.com/nanchengyimeng/ghttp
 
-go 1.18
+go 1.21
+
+require (
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	google.golang.org/protobuf v1.34.1
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
@@ -0,0 +1,73 @@
+package ghttp
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ByHost 按请求的host分组，可用于 RateLimitOptions.KeyFunc 及 CircuitBreakerOptions.KeyFunc
+func ByHost(request *http.Request) string {
+	return request.URL.Host
+}
+
+// 按key分组管理的对象，如限流的令牌桶、熔断器
+// 分组超过 idleTimeout 未使用且 idle 返回 true 时删除，下次使用时重新创建
+type keyedGroups[T any] struct {
+	idleTimeout time.Duration
+
+	//创建分组
+	create func(key string) T
+
+	//分组是否可以删除，只有与新建分组等价时才可删除
+	idle func(value T, now time.Time) bool
+
+	mu     sync.Mutex
+	groups map[string]*keyedGroup[T]
+
+	//上次清理的时间，清理需要遍历全部分组，间隔 idleTimeout 才执行一次
+	swept time.Time
+}
+
+type keyedGroup[T any] struct {
+	value T
+
+	//最后一次获取该分组的时间
+	used time.Time
+}
+
+func newKeyedGroups[T any](idleTimeout time.Duration, create func(key string) T, idle func(value T, now time.Time) bool) *keyedGroups[T] {
+	return &keyedGroups[T]{
+		idleTimeout: idleTimeout,
+		create:      create,
+		idle:        idle,
+		groups:      make(map[string]*keyedGroup[T]),
+	}
+}
+
+// 获取key对应的分组，不存在时创建
+func (g *keyedGroups[T]) get(key string) T {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if now.Sub(g.swept) >= g.idleTimeout {
+		g.sweep(now)
+	}
+	group, ok := g.groups[key]
+	if !ok {
+		group = &keyedGroup[T]{value: g.create(key)}
+		g.groups[key] = group
+	}
+	group.used = now
+	return group.value
+}
+
+func (g *keyedGroups[T]) sweep(now time.Time) {
+	for key, group := range g.groups {
+		if now.Sub(group.used) >= g.idleTimeout && g.idle(group.value, now) {
+			delete(g.groups, key)
+		}
+	}
+	g.swept = now
+}
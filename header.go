@@ -22,4 +22,6 @@ const (
 	HTTP_CONTENT_TYPE_TEXT      = "text/plain"
 	HTTP_CONTENT_TYPE_JSON      = "application/json"
 	HTTP_CONTENT_TYPE_XML       = "application/xml"
+	HTTP_CONTENT_TYPE_MSGPACK   = "application/msgpack"
+	HTTP_CONTENT_TYPE_PROTOBUF  = "application/x-protobuf"
 )
@@ -0,0 +1,341 @@
+package ghttp
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogFileOptions 日志文件的切分及清理设置，零值表示只按天切分，不清理、不压缩
+type LogFileOptions struct {
+	//单个日志文件的最大字节数，超出时切分为 YYYYMMDD.N.log，<=0 时不按大小切分
+	MaxSize int64
+
+	//保留的历史日志文件数，不含正在写入的文件，<=0 时不限制
+	MaxBackups int
+
+	//历史日志文件的保留时长，按修改时间计算，<=0 时不限制
+	MaxAge time.Duration
+
+	//是否使用gzip压缩历史日志文件
+	Compress bool
+}
+
+// 日志文件名，如 20060102.log、20060102.1.log、20060102.1.log.gz
+var logFileNamePattern = regexp.MustCompile(`^\d{8}(\.\d+)?\.log(\.gz)?$`)
+
+// 按天及大小切分的日志文件，保持文件句柄打开，并发安全
+type rotatingFile struct {
+	dir     string
+	options LogFileOptions
+
+	mu     sync.Mutex
+	file   *os.File
+	day    string
+	size   int64
+	closed bool
+
+	//压缩及清理历史文件，在后台串行执行
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+
+	//在 logFiles 中的key及引用数，由 logFilesMu 保护
+	key  string
+	refs int
+}
+
+func newRotatingFile(dir string, options LogFileOptions) *rotatingFile {
+	return &rotatingFile{dir: dir, options: options}
+}
+
+// 同一目录的日志文件在进程内共用一个 rotatingFile
+// 多个 client 各自切分时，一个 client 重命名并压缩的文件仍会被其他 client 写入，导致日志丢失
+var (
+	logFilesMu sync.Mutex
+	logFiles   = make(map[string]*rotatingFile)
+)
+
+// 获取目录对应的日志文件，目录已被其他 client 使用时共用同一个文件，切分及清理沿用首个 client 的设置
+func openLogFile(dir string, options LogFileOptions) *logFileRef {
+	key := dir
+	if abs, err := filepath.Abs(dir); err == nil {
+		key = abs
+	}
+
+	logFilesMu.Lock()
+	defer logFilesMu.Unlock()
+	file, ok := logFiles[key]
+	if !ok {
+		file = newRotatingFile(dir, options)
+		file.key = key
+		logFiles[key] = file
+	}
+	file.refs++
+	return &logFileRef{file: file}
+}
+
+// 释放一个引用，最后一个引用释放时关闭文件
+func releaseLogFile(file *rotatingFile) error {
+	logFilesMu.Lock()
+	file.refs--
+	last := file.refs <= 0
+	if last {
+		delete(logFiles, file.key)
+	}
+	logFilesMu.Unlock()
+
+	if !last {
+		return nil
+	}
+	return file.Close()
+}
+
+// 单个 client 持有的日志文件引用，关闭后该 client 的写入返回 os.ErrClosed，不影响共用该文件的其他 client
+type logFileRef struct {
+	file   *rotatingFile
+	closed atomic.Bool
+	once   sync.Once
+}
+
+func (r *logFileRef) Write(p []byte) (int, error) {
+	if r.closed.Load() {
+		return 0, os.ErrClosed
+	}
+	return r.file.Write(p)
+}
+
+// Close 释放引用，多次调用只释放一次
+func (r *logFileRef) Close() error {
+	var err error
+	r.once.Do(func() {
+		r.closed.Store(true)
+		err = releaseLogFile(r.file)
+	})
+	return err
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	day := time.Now().Format("20060102")
+	if f.file == nil || f.day != day {
+		//切换日期时，前一天的文件成为历史文件
+		rolled := f.file != nil
+		if err := f.open(day); err != nil {
+			log.Println("打开日志文件失败: " + err.Error())
+			return 0, err
+		}
+		if rolled {
+			f.mill()
+		}
+	}
+	if f.options.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.options.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// 打开当天的日志文件，切换日期时关闭前一天的文件
+func (f *rotatingFile) open(day string) error {
+	if f.file != nil {
+		_ = f.file.Close()
+		f.file = nil
+	}
+	if err := os.MkdirAll(f.dir, 0711); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path(day), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file, f.day, f.size = file, day, info.Size()
+	return nil
+}
+
+// 当前文件超出大小时，重命名为 YYYYMMDD.N.log，并重新打开
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	current := f.path(f.day)
+	for i := 1; ; i++ {
+		backup := filepath.Join(f.dir, f.day+"."+strconv.Itoa(i)+".log")
+		if exists(backup) || exists(backup+".gz") {
+			continue
+		}
+		if err := os.Rename(current, backup); err != nil {
+			return err
+		}
+		break
+	}
+
+	if err := f.open(f.day); err != nil {
+		return err
+	}
+	f.mill()
+	return nil
+}
+
+func (f *rotatingFile) path(day string) string {
+	return filepath.Join(f.dir, day+".log")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// 在后台压缩及清理历史文件，需在打开新文件之后调用
+func (f *rotatingFile) mill() {
+	if !f.options.Compress && f.options.MaxBackups <= 0 && f.options.MaxAge <= 0 {
+		return
+	}
+	f.millWg.Add(1)
+	go func() {
+		defer f.millWg.Done()
+		f.millMu.Lock()
+		defer f.millMu.Unlock()
+		if err := f.millOnce(f.activeName()); err != nil {
+			log.Println("清理日志文件失败: " + err.Error())
+		}
+	}()
+}
+
+// 正在写入的文件名，执行清理时读取，避免清理期间切换了文件
+func (f *rotatingFile) activeName() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return filepath.Base(f.path(f.day))
+}
+
+func (f *rotatingFile) millOnce(active string) error {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return err
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == active || !logFileNamePattern.MatchString(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(f.dir, name), modTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+
+	var errs []error
+	for i, b := range backups {
+		expired := f.options.MaxAge > 0 && time.Since(b.modTime) > f.options.MaxAge
+		if (f.options.MaxBackups > 0 && i >= f.options.MaxBackups) || expired {
+			if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if f.options.Compress && filepath.Ext(b.path) != ".gz" {
+			if err := gzipFile(b.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 压缩文件为 path.gz，成功后删除原文件，保留原文件的修改时间
+func gzipFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if err = os.Chtimes(path+".gz", info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	_ = src.Close()
+	return os.Remove(path)
+}
+
+// Close 将内容写入磁盘并关闭文件，等待后台的压缩及清理完成，关闭后写入返回 os.ErrClosed
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	var err error
+	if f.file != nil {
+		err = f.file.Sync()
+		if closeErr := f.file.Close(); err == nil {
+			err = closeErr
+		}
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.millWg.Wait()
+	if err != nil {
+		return fmt.Errorf("ghttp: close log file: %w", err)
+	}
+	return nil
+}
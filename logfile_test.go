@@ -0,0 +1,85 @@
+package ghttp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileDayRollover(t *testing.T) {
+	dir := t.TempDir()
+	f := newRotatingFile(dir, LogFileOptions{Compress: true, MaxBackups: 5})
+
+	//模拟前一天已打开的日志文件
+	yesterday := "20000101"
+	if err := f.open(yesterday); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.file.Write([]byte("yesterday\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("today\n")); err != nil {
+		t.Fatal(err)
+	}
+	f.millWg.Wait()
+	if _, err := f.Write([]byte("after mill\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	today := filepath.Join(dir, time.Now().Format("20060102")+".log")
+	content, err := os.ReadFile(today)
+	if err != nil {
+		t.Fatalf("active log file removed: %v", err)
+	}
+	if string(content) != "today\nafter mill\n" {
+		t.Fatalf("unexpected active log content %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, yesterday+".log.gz")); err != nil {
+		t.Fatalf("previous day log not compressed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, yesterday+".log")); !os.IsNotExist(err) {
+		t.Fatalf("previous day log not removed after compress: %v", err)
+	}
+}
+
+func TestLogFileSharedByDir(t *testing.T) {
+	dir := t.TempDir()
+	first := openLogFile(dir, LogFileOptions{MaxSize: 10, Compress: true})
+	second := openLogFile(dir, LogFileOptions{})
+	if first.file != second.file {
+		t.Fatal("clients using the same dir do not share the log file")
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := first.Write([]byte("first\n")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := second.Write([]byte("second\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Write([]byte("closed\n")); err != os.ErrClosed {
+		t.Fatalf("write after close returned %v", err)
+	}
+	if _, err := second.Write([]byte("still open\n")); err != nil {
+		t.Fatalf("closing one client closed the shared file: %v", err)
+	}
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	logFilesMu.Lock()
+	_, ok := logFiles[first.file.key]
+	logFilesMu.Unlock()
+	if ok {
+		t.Fatal("log file not released after the last close")
+	}
+}
@@ -0,0 +1,241 @@
+package ghttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// Logger 请求日志的输出，*slog.Logger 实现了该接口，可直接通过 ClientBuilder.SetLogger 设置
+type Logger interface {
+	// Enabled 是否输出该级别的日志
+	Enabled(ctx context.Context, level slog.Level) bool
+
+	// LogAttrs 输出一条日志
+	LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr)
+}
+
+// LogLevels 请求日志按结果使用的级别
+type LogLevels struct {
+	//2xx、3xx 响应
+	Success slog.Level
+
+	//4xx 响应
+	ClientError slog.Level
+
+	//5xx 响应
+	ServerError slog.Level
+
+	//超时、连接失败等传输错误
+	Failure slog.Level
+
+	//请求头、请求及响应的body，日志输出启用该级别时才记录
+	Body slog.Level
+}
+
+// DefaultLogLevels 默认的日志级别：成功为Info，4xx为Warn，5xx及传输错误为Error，body为Debug
+func DefaultLogLevels() LogLevels {
+	return LogLevels{
+		Success:     slog.LevelInfo,
+		ClientError: slog.LevelWarn,
+		ServerError: slog.LevelError,
+		Failure:     slog.LevelError,
+		Body:        slog.LevelDebug,
+	}
+}
+
+// 根据响应获取日志级别，ErrorOnStatus 产生的 *HTTPError 按状态码区分
+func (l LogLevels) level(resp IResponse) slog.Level {
+	var httpErr *HTTPError
+	if err := resp.Error(); err != nil && !errors.As(err, &httpErr) {
+		return l.Failure
+	}
+	switch code := resp.StatusCode(); {
+	case code >= 500:
+		return l.ServerError
+	case code >= 400:
+		return l.ClientError
+	}
+	return l.Success
+}
+
+// 请求日志的消息
+const logMessage = "ghttp request"
+
+// 日志中body的默认最大长度
+const defaultLogBodyLimit = 4096
+
+// 内置的日志输出，以 key=value 的文本格式写入 w
+func newTextLogger(w io.Writer, level slog.Leveler) Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// 一次请求的日志，同一份内容输出到所有日志输出，每次重试单独记录
+type logRecord struct {
+	client   *client
+	ctx      context.Context
+	request  *http.Request
+	resp     IResponse
+	level    slog.Level
+	attrs    []slog.Attr
+	withBody []slog.Attr
+}
+
+// 构造日志的属性，url、请求头及body均已脱敏
+func (c *client) newLogRecord(ctx context.Context, request *http.Request, resp IResponse) *logRecord {
+	attrs := []slog.Attr{
+		slog.String("method", request.Method),
+		slog.String("url", c.redactor.url(request.URL)),
+		slog.Int("status", resp.StatusCode()),
+	}
+	if startTime, ok := ctx.Value(ctxKeyStartTime).(time.Time); ok {
+		attrs = append(attrs, slog.Duration("duration", time.Since(startTime)))
+	}
+	if id := c.requestUniqueId(ctx); id != "" {
+		attrs = append(attrs, slog.String("unique_id", id))
+	}
+	attrs = append(attrs,
+		slog.Int64("request_size", request.ContentLength),
+		slog.Int64("response_size", responseSize(resp)),
+	)
+	if attempt, ok := ctx.Value(ctxKeyAttempt).(int); ok {
+		attrs = append(attrs, slog.Int("attempt", attempt))
+	}
+	if err := resp.Error(); err != nil {
+		attrs = append(attrs,
+			slog.String("error", c.redactor.errorText(err)),
+			slog.String("error_kind", logErrorKind(err)),
+		)
+	}
+
+	return &logRecord{
+		client:  c,
+		ctx:     ctx,
+		request: request,
+		resp:    resp,
+		level:   c.logLevels.level(resp),
+		attrs:   c.redactor.apply(attrs),
+	}
+}
+
+// 包含请求头及body的属性，首次使用时构造
+func (r *logRecord) bodyAttrs() []slog.Attr {
+	if r.withBody != nil {
+		return r.withBody
+	}
+	c := r.client
+	extra := []slog.Attr{slog.Any("request_header", c.redactor.header(r.request.Header))}
+	if logEnabled(r.ctx, ctxKeyLogRequestBody, c.logRequestBody) {
+		body, _ := r.ctx.Value(ctxKeyBody).(string)
+		extra = append(extra, slog.String("request_body", c.logBodyContent(r.request.Header.Get("Content-Type"), body)))
+	}
+	if logEnabled(r.ctx, ctxKeyLogResponseBody, c.logResponseBody) {
+		content := logResponseContent(r.resp)
+		if !r.resp.Streamed() {
+			content = c.logBodyContent(r.resp.Header().Get("Content-Type"), content)
+		}
+		extra = append(extra, slog.String("response_body", content))
+	}
+	extra = c.redactor.apply(extra)
+	r.withBody = append(r.attrs[:len(r.attrs):len(r.attrs)], extra...)
+	return r.withBody
+}
+
+// 输出到 logger，logger 启用了 LogLevels.Body 级别时记录请求头及body
+func (r *logRecord) write(logger Logger) {
+	if !logger.Enabled(r.ctx, r.level) {
+		return
+	}
+	attrs := r.attrs
+	if logger.Enabled(r.ctx, r.client.logLevels.Body) {
+		attrs = r.bodyAttrs()
+	}
+	logger.LogAttrs(r.ctx, r.level, logMessage, attrs...)
+}
+
+// 日志中错误的分类
+func logErrorKind(err error) string {
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		return "http_status"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrDNS):
+		return "dns"
+	case errors.Is(err, ErrTLS):
+		return "tls"
+	case errors.Is(err, ErrConnectionRefused):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return "connection_reset"
+	}
+	return "unknown"
+}
+
+// 请求单独设置了是否记录body时以请求的设置为准
+func logEnabled(ctx context.Context, key ctxKey, enabled bool) bool {
+	if v, ok := ctx.Value(key).(bool); ok {
+		return v
+	}
+	return enabled
+}
+
+// 日志中记录的body：非文本类型只记录长度，文本类型脱敏后按 logBodyLimit 截断
+func (c *client) logBodyContent(contentType, body string) string {
+	if body == "" {
+		return body
+	}
+	if !isTextContent(contentType, body) {
+		return fmt.Sprintf("<binary %d bytes>", len(body))
+	}
+	body = c.redactor.body(contentType, body)
+	if c.logBodyLimit <= 0 || len(body) <= c.logBodyLimit {
+		return body
+	}
+	//在完整的utf8字符处截断
+	end := c.logBodyLimit
+	for end > 0 && !utf8.RuneStart(body[end]) {
+		end--
+	}
+	return fmt.Sprintf("%s...<truncated %d bytes>", body[:end], len(body)-end)
+}
+
+// 是否为文本类型，未设置 Content-Type 时按内容是否为合法的utf8判断
+func isTextContent(contentType, body string) bool {
+	media := mediaType(contentType)
+	if media == "" {
+		return utf8.ValidString(body)
+	}
+	if strings.HasPrefix(media, "text/") || strings.HasSuffix(media, "+json") || strings.HasSuffix(media, "+xml") {
+		return true
+	}
+	switch media {
+	case HTTP_CONTENT_TYPE_JSON, HTTP_CONTENT_TYPE_XML, HTTP_CONTENT_TYPE_FROM_DATA,
+		"application/javascript", "application/x-javascript", "application/ecmascript",
+		"application/yaml", "application/x-yaml", "application/graphql", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// 响应的大小，流式响应未读取body，使用 Content-Length，未知时为 -1
+func responseSize(resp IResponse) int64 {
+	if resp.Streamed() {
+		return resp.ContentLength()
+	}
+	return int64(len(resp.Content()))
+}
@@ -0,0 +1,77 @@
+package ghttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// Handler 处理一次HTTP请求并返回响应
+type Handler func(ctx context.Context, request *http.Request) IResponse
+
+// Middleware 请求中间件，包装下一个 Handler
+// 中间件可以修改 request、ctx 及响应，也可以不调用 next 直接返回响应（如缓存、mock）
+type Middleware func(next Handler) Handler
+
+// 组装中间件链，顺序为：
+// 内置日志中间件 -> 通过 ClientBuilder.Use 添加的中间件（先添加的在外层） -> 内置熔断中间件（设置了熔断时） -> 内置限流中间件（设置了限流时） -> 内置响应构造 buildResponse
+// 熔断及限流位于 Use 添加的中间件之内，中间件直接返回响应（如缓存、mock）时不占用令牌，也不计入熔断
+func (c *client) buildHandler(middlewares []Middleware) Handler {
+	handler := c.buildResponseHandler
+	if c.rateLimiter != nil {
+		handler = c.rateLimitMiddleware(handler)
+	}
+	if c.circuitBreakers != nil {
+		handler = c.circuitBreakerMiddleware(handler)
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return c.loggerMiddleware(handler)
+}
+
+// 内置中间件：记录请求耗时并输出日志，位于中间件链的最外层
+// 响应携带实际发出的请求时（如中间件 Clone 后签名的请求），记录该请求，否则记录传入的请求；重定向时记录重定向前的首个请求
+func (c *client) loggerMiddleware(next Handler) Handler {
+	return func(ctx context.Context, request *http.Request) IResponse {
+		ctx = c.buildStartTime(ctx)
+		resp := next(ctx, request)
+		if sent := resp.Request(); sent != nil {
+			for sent.Response != nil && sent.Response.Request != nil {
+				sent = sent.Response.Request
+			}
+			request = sent
+		}
+		return c.logger(ctx, request, resp)
+	}
+}
+
+// 内置处理：发起HTTP请求，并由 buildResponse 构造响应，位于中间件链的最内层
+// 请求设置了流式响应时，使用 StreamBuildResponse 构造响应；设置了下载进度回调时，读取body时回调进度
+func (c *client) buildResponseHandler(ctx context.Context, request *http.Request) IResponse {
+	buildResponse := c.buildResponse
+	if stream, _ := ctx.Value(ctxKeyStream).(bool); stream {
+		buildResponse = StreamBuildResponse
+	}
+
+	if sent, ok := ctx.Value(ctxKeySent).(*bool); ok {
+		*sent = true
+	}
+	ctx, response, err := c.doRequest(ctx, request)
+	if option, ok := ctx.Value(ctxKeyDownloadProgress).(*progressOption); ok && response != nil {
+		response.Body = newProgressReadCloser(response.Body, response.ContentLength, option)
+	}
+
+	_, resp := buildResponse(ctx, response, err)
+	if httpResponse, ok := resp.(*HttpResponse); ok {
+		if httpResponse.codecs == nil {
+			httpResponse.codecs = c.codecs
+		}
+		if httpResponse.redactor == nil {
+			httpResponse.redactor = c.redactor
+		}
+	}
+	if c.errorOnStatus != nil && resp.Error() == nil && c.errorOnStatus(resp.StatusCode()) {
+		return &statusErrorResponse{IResponse: resp, err: newHTTPError(resp)}
+	}
+	return resp
+}
@@ -2,9 +2,15 @@ package ghttp
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strings"
 )
 
 type MultipartDataType int8
@@ -26,8 +32,8 @@ type IMultipart interface {
 }
 
 type EasyMultipart struct {
-	//multipart 内容
-	buf *bytes.Buffer
+	//multipart 内容，支持 Seek，重试时可重新读取
+	buf *bytes.Reader
 
 	//随机生成的用于multipart 的 boundary字符串
 	contentType string
@@ -41,79 +47,129 @@ func (m *EasyMultipart) Read(p []byte) (n int, err error) {
 	return m.buf.Read(p)
 }
 
+// Seek 实现 io.Seeker，用于重试时重置读取位置
+func (m *EasyMultipart) Seek(offset int64, whence int) (int64, error) {
+	return m.buf.Seek(offset, whence)
+}
+
 //用于 `MultipartBuilder` 中的内容记录
 type MultipartDataContent struct {
 	Type    MultipartDataType
 	Content []byte
+
+	//form-data的name，同一个name允许出现多次
+	Name string
+
+	//文件名，为空时 AddFile 使用文件路径中的文件名，AddBytes 使用name
+	FileName string
+
+	//part的 Content-Type，为空时根据文件扩展名或内容自动识别
+	ContentType string
+
+	//part的其他头部信息
+	Header textproto.MIMEHeader
 }
 
-//用于构造一个 multipart
+//PartOptions 文件类型part的可选设置
+type PartOptions struct {
+	//文件名
+	FileName string
+
+	//part的 Content-Type，为空时根据文件扩展名或内容自动识别
+	ContentType string
+
+	//part的其他头部信息，如 Content-ID
+	Header textproto.MIMEHeader
+}
+
+//用于构造一个 multipart，part按添加顺序写入
 type MultipartBuilder struct {
-	content map[string]*MultipartDataContent
+	content []*MultipartDataContent
 }
 
 //初始化一个 multipart 构造器
 func NewMultipartBuilder() *MultipartBuilder {
-	return &MultipartBuilder{
-		content: make(map[string]*MultipartDataContent),
-	}
+	return &MultipartBuilder{}
 }
 
 //添加文件
 //name upload时的name
 //fileName 文件的路径+文件名
 func (m *MultipartBuilder) AddFile(name, fileName string) *MultipartBuilder {
-	m.content[name] = &MultipartDataContent{
-		Type:    MultipartDataTypeFile,
-		Content: []byte(fileName),
-	}
+	return m.AddFileWithOptions(name, fileName, nil)
+}
+
+//添加文件，并设置文件名、Content-Type 及其他头部信息
+func (m *MultipartBuilder) AddFileWithOptions(name, fileName string, opts *PartOptions) *MultipartBuilder {
+	m.content = append(m.content, newMultipartDataContent(MultipartDataTypeFile, name, []byte(fileName), opts))
 	return m
 }
 
 //添加form-data数据
 func (m *MultipartBuilder) AddFromData(name, value string) *MultipartBuilder {
-	m.content[name] = &MultipartDataContent{
-		Type:    MultipartDataTypeFormData,
-		Content: []byte(value),
-	}
+	return m.AddFromDataWithOptions(name, value, nil)
+}
+
+//添加form-data数据，并设置 Content-Type 及其他头部信息，opts.FileName 不生效
+func (m *MultipartBuilder) AddFromDataWithOptions(name, value string, opts *PartOptions) *MultipartBuilder {
+	data := newMultipartDataContent(MultipartDataTypeFormData, name, []byte(value), opts)
+	data.FileName = ""
+	m.content = append(m.content, data)
 	return m
 }
 
 //以map的形式 添加form-data数据, k=>form-data的name, v=>form-data的Valve
+//map无序，按name排序后添加
 func (m *MultipartBuilder) FromData(value map[string]string) *MultipartBuilder {
-	for k, v := range value {
-		m.content[k] = &MultipartDataContent{
-			Type:    MultipartDataTypeFormData,
-			Content: []byte(v),
-		}
+	for _, k := range sortedKeys(value) {
+		m.AddFromData(k, value[k])
 	}
 	return m
 }
 
 //添加[]byte, name, form-data的name
 func (m *MultipartBuilder) AddBytes(name string, bytes []byte) *MultipartBuilder {
-	m.content[name] = &MultipartDataContent{
-		Type:    MultipartDataTypeContent,
-		Content: bytes,
-	}
+	return m.AddBytesWithOptions(name, bytes, nil)
+}
+
+//添加[]byte，并设置文件名、Content-Type 及其他头部信息
+func (m *MultipartBuilder) AddBytesWithOptions(name string, bytes []byte, opts *PartOptions) *MultipartBuilder {
+	m.content = append(m.content, newMultipartDataContent(MultipartDataTypeContent, name, bytes, opts))
 	return m
 }
 
+func newMultipartDataContent(dataType MultipartDataType, name string, content []byte, opts *PartOptions) *MultipartDataContent {
+	data := &MultipartDataContent{
+		Type:    dataType,
+		Content: content,
+		Name:    name,
+	}
+	if opts != nil {
+		data.FileName = opts.FileName
+		data.ContentType = opts.ContentType
+		data.Header = opts.Header
+	}
+	return data
+}
+
 //构造 MultipartData
 func (m *MultipartBuilder) Builder() (*EasyMultipart, error) {
 	buf := new(bytes.Buffer)
 	mulWriter := multipart.NewWriter(buf)
-	defer mulWriter.Close()
 
-	for name, content := range m.content {
+	for _, content := range m.content {
 		switch content.Type {
 		case MultipartDataTypeFile:
 			//文件类型,读取文件,写入buf
-			formFile, err := mulWriter.CreateFormFile(name, name)
+			file, err := ioutil.ReadFile(string(content.Content))
 			if err != nil {
 				return nil, err
 			}
-			file, err := ioutil.ReadFile(string(content.Content))
+			fileName := content.FileName
+			if fileName == "" {
+				fileName = filepath.Base(string(content.Content))
+			}
+			formFile, err := mulWriter.CreatePart(filePartHeader(content.Name, fileName, content.ContentType, content.Header, file))
 			if err != nil {
 				return nil, err
 			}
@@ -123,13 +179,21 @@ func (m *MultipartBuilder) Builder() (*EasyMultipart, error) {
 			}
 		case MultipartDataTypeFormData:
 			//form-data类型,直接写入
-			err := mulWriter.WriteField(name, string(content.Content))
+			field, err := mulWriter.CreatePart(fieldPartHeader(content.Name, content.ContentType, content.Header))
+			if err != nil {
+				return nil, err
+			}
+			_, err = field.Write(content.Content)
 			if err != nil {
 				return nil, err
 			}
 		case MultipartDataTypeContent:
 			//[]byte 类型,当做文件写入
-			formFile, err := mulWriter.CreateFormFile(name, name)
+			fileName := content.FileName
+			if fileName == "" {
+				fileName = content.Name
+			}
+			formFile, err := mulWriter.CreatePart(filePartHeader(content.Name, fileName, content.ContentType, content.Header, content.Content))
 			if err != nil {
 				return nil, err
 			}
@@ -139,8 +203,49 @@ func (m *MultipartBuilder) Builder() (*EasyMultipart, error) {
 			}
 		}
 	}
+	//写入结尾的 boundary
+	if err := mulWriter.Close(); err != nil {
+		return nil, err
+	}
 	return &EasyMultipart{
-		buf:         buf,
+		buf:         bytes.NewReader(buf.Bytes()),
 		contentType: mulWriter.FormDataContentType(),
 	}, nil
 }
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+//生成form-data字段的头部，contentType 为空时不设置 Content-Type
+func fieldPartHeader(name, contentType string, header textproto.MIMEHeader) textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader, len(header)+2)
+	for k, v := range header {
+		h[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(name)))
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	return h
+}
+
+//生成文件类型part的头部
+//contentType 为空时，先根据文件扩展名识别，再根据内容的前512个字节识别
+func filePartHeader(name, fileName, contentType string, header textproto.MIMEHeader, sniff []byte) textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader, len(header)+2)
+	for k, v := range header {
+		h[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(name), quoteEscaper.Replace(fileName)))
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(fileName))
+	}
+	if contentType == "" && len(sniff) != 0 {
+		contentType = http.DetectContentType(sniff)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	return h
+}
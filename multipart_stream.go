@@ -0,0 +1,354 @@
+package ghttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 流式 multipart 中单个 part 的内容来源
+type streamPart struct {
+	//form-data的name
+	name string
+
+	//文件名
+	fileName string
+
+	//form-data字段的值
+	value string
+
+	//文件路径，发送时才打开读取
+	path string
+
+	//任意的 io.Reader
+	reader io.Reader
+
+	//内容长度，未知时为 -1
+	size int64
+
+	//part的 Content-Type，为空时自动识别
+	contentType string
+
+	//part的其他头部信息
+	header textproto.MIMEHeader
+}
+
+// StreamMultipartBuilder 用于构造一个流式的 multipart
+// 与 MultipartBuilder 不同，文件及 io.Reader 的内容不会预先读入内存，而是在发送请求时边读边发
+type StreamMultipartBuilder struct {
+	parts []*streamPart
+}
+
+// NewStreamMultipartBuilder 初始化一个流式 multipart 构造器
+func NewStreamMultipartBuilder() *StreamMultipartBuilder {
+	return &StreamMultipartBuilder{}
+}
+
+// AddFile 添加文件，name upload时的name，path 文件的路径+文件名
+func (m *StreamMultipartBuilder) AddFile(name, path string) *StreamMultipartBuilder {
+	return m.AddFileWithOptions(name, path, nil)
+}
+
+// AddFileWithOptions 添加文件，并设置文件名、Content-Type 及其他头部信息
+func (m *StreamMultipartBuilder) AddFileWithOptions(name, path string, opts *PartOptions) *StreamMultipartBuilder {
+	part := &streamPart{
+		name:     name,
+		fileName: filepath.Base(path),
+		path:     path,
+		size:     -1,
+	}
+	m.parts = append(m.parts, part.withOptions(opts))
+	return m
+}
+
+// AddReader 添加 io.Reader 作为文件内容，size 未知时传 -1，此时请求以 chunked 方式发送
+// reader 实现了 io.Seeker 时，请求支持重试
+func (m *StreamMultipartBuilder) AddReader(name, fileName string, reader io.Reader, size int64) *StreamMultipartBuilder {
+	return m.AddReaderWithOptions(name, reader, size, &PartOptions{FileName: fileName})
+}
+
+// AddReaderWithOptions 添加 io.Reader 作为文件内容，并设置文件名、Content-Type 及其他头部信息
+// 未设置 Content-Type 时根据文件扩展名识别，无法识别时读取前512个字节识别
+func (m *StreamMultipartBuilder) AddReaderWithOptions(name string, reader io.Reader, size int64, opts *PartOptions) *StreamMultipartBuilder {
+	part := &streamPart{
+		name:     name,
+		fileName: name,
+		reader:   reader,
+		size:     size,
+	}
+	m.parts = append(m.parts, part.withOptions(opts))
+	return m
+}
+
+func (p *streamPart) withOptions(opts *PartOptions) *streamPart {
+	if opts == nil {
+		return p
+	}
+	if opts.FileName != "" {
+		p.fileName = opts.FileName
+	}
+	p.contentType = opts.ContentType
+	p.header = opts.Header
+	return p
+}
+
+// AddFromData 添加form-data数据
+func (m *StreamMultipartBuilder) AddFromData(name, value string) *StreamMultipartBuilder {
+	return m.AddFromDataWithOptions(name, value, nil)
+}
+
+// AddFromDataWithOptions 添加form-data数据，并设置 Content-Type 及其他头部信息，opts.FileName 不生效
+func (m *StreamMultipartBuilder) AddFromDataWithOptions(name, value string, opts *PartOptions) *StreamMultipartBuilder {
+	part := &streamPart{
+		name:  name,
+		value: value,
+		size:  int64(len(value)),
+	}
+	m.parts = append(m.parts, part.withOptions(opts))
+	return m
+}
+
+// FromData 以map的形式 添加form-data数据, k=>form-data的name, v=>form-data的Valve
+// map无序，按name排序后添加
+func (m *StreamMultipartBuilder) FromData(value map[string]string) *StreamMultipartBuilder {
+	for _, k := range sortedKeys(value) {
+		m.AddFromData(k, value[k])
+	}
+	return m
+}
+
+// Builder 构造 StreamMultipart，此时只读取文件大小，不读取文件内容
+func (m *StreamMultipartBuilder) Builder() (*StreamMultipart, error) {
+	var buf bytes.Buffer
+	mulWriter := multipart.NewWriter(&buf)
+
+	sm := &StreamMultipart{
+		contentType: mulWriter.FormDataContentType(),
+		parts:       make([]*streamPart, len(m.parts)),
+		headers:     make([][]byte, len(m.parts)),
+		length:      0,
+	}
+
+	for i, part := range m.parts {
+		p := *part
+		if p.path != "" {
+			info, err := os.Stat(p.path)
+			if err != nil {
+				return nil, err
+			}
+			p.size = info.Size()
+		}
+		if seeker, ok := p.reader.(io.Seeker); ok {
+			offset, err := seeker.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			p.reader = &seekableSection{seeker: p.reader.(io.ReadSeeker), offset: offset}
+		}
+		sm.parts[i] = &p
+
+		header, err := streamPartHeader(&p)
+		if err != nil {
+			return nil, err
+		}
+
+		//记录每个part的头部，内容在发送时再读取
+		buf.Reset()
+		if _, err := mulWriter.CreatePart(header); err != nil {
+			return nil, err
+		}
+		sm.headers[i] = append([]byte(nil), buf.Bytes()...)
+
+		if sm.length >= 0 && p.size >= 0 {
+			sm.length += int64(len(sm.headers[i])) + p.size
+		} else {
+			sm.length = -1
+		}
+	}
+
+	//写入结尾的 boundary
+	buf.Reset()
+	if err := mulWriter.Close(); err != nil {
+		return nil, err
+	}
+	sm.trailer = append([]byte(nil), buf.Bytes()...)
+	if sm.length >= 0 {
+		sm.length += int64(len(sm.trailer))
+	}
+
+	if err := sm.rewind(); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// 生成part的头部，文件及 io.Reader 未设置 Content-Type 且无法从扩展名识别时，读取前512个字节识别
+// 可重读的 io.Reader 识别后回到初始位置，否则替换为带缓冲的 bufio.Reader，已读取的内容在发送时仍会写入
+func streamPartHeader(p *streamPart) (textproto.MIMEHeader, error) {
+	if p.path == "" && p.reader == nil {
+		return fieldPartHeader(p.name, p.contentType, p.header), nil
+	}
+	if p.contentType != "" || mime.TypeByExtension(filepath.Ext(p.fileName)) != "" {
+		return filePartHeader(p.name, p.fileName, p.contentType, p.header, nil), nil
+	}
+
+	var sniff []byte
+	switch reader := p.reader.(type) {
+	case nil:
+		file, err := os.Open(p.path)
+		if err != nil {
+			return nil, err
+		}
+		sniff, err = readSniff(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+	case *seekableSection:
+		var err error
+		if sniff, err = readSniff(reader); err != nil {
+			return nil, err
+		}
+		if err := reader.rewind(); err != nil {
+			return nil, err
+		}
+	default:
+		buffered := bufio.NewReaderSize(reader, 512)
+		sniff, _ = buffered.Peek(512)
+		p.reader = buffered
+	}
+	return filePartHeader(p.name, p.fileName, p.contentType, p.header, sniff), nil
+}
+
+// 读取用于识别 Content-Type 的前512个字节，内容不足512个字节时返回全部内容
+func readSniff(reader io.Reader) ([]byte, error) {
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(reader, sniff)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return sniff[:n], nil
+}
+
+// StreamMultipart 流式的 multipart，实现了 IMultipart
+// 所有part的长度已知时，请求携带 Content-Length，否则以 chunked 方式发送
+type StreamMultipart struct {
+	contentType string
+
+	parts   []*streamPart
+	headers [][]byte
+	trailer []byte
+
+	//总长度，未知时为 -1
+	length int64
+
+	//当前的读取流
+	reader io.Reader
+
+	//当前打开的文件
+	file *os.File
+}
+
+func (m *StreamMultipart) ContentType() string {
+	return m.contentType
+}
+
+// ContentLength 返回multipart的总长度，未知时返回 -1
+func (m *StreamMultipart) ContentLength() int64 {
+	return m.length
+}
+
+func (m *StreamMultipart) Read(p []byte) (n int, err error) {
+	n, err = m.reader.Read(p)
+	if err == io.EOF {
+		m.closeFile()
+	}
+	return n, err
+}
+
+// Close 关闭读取中的文件
+func (m *StreamMultipart) Close() error {
+	m.closeFile()
+	return nil
+}
+
+func (m *StreamMultipart) closeFile() {
+	if m.file != nil {
+		_ = m.file.Close()
+		m.file = nil
+	}
+}
+
+// 重置读取位置，用于重试时重新发送，存在无法重读的 io.Reader 时返回错误
+func (m *StreamMultipart) rewind() error {
+	m.closeFile()
+	readers := make([]io.Reader, 0, len(m.parts)*2+1)
+	for i, part := range m.parts {
+		readers = append(readers, bytes.NewReader(m.headers[i]))
+		switch {
+		case part.path != "":
+			readers = append(readers, &lazyFileReader{multipart: m, path: part.path})
+		case part.reader != nil:
+			section, ok := part.reader.(*seekableSection)
+			if m.reader != nil && !ok {
+				return errors.New("multipart reader can not be rewound")
+			}
+			if ok {
+				if err := section.rewind(); err != nil {
+					return err
+				}
+			}
+			readers = append(readers, part.reader)
+		default:
+			readers = append(readers, strings.NewReader(part.value))
+		}
+	}
+	readers = append(readers, bytes.NewReader(m.trailer))
+	m.reader = io.MultiReader(readers...)
+	return nil
+}
+
+// 首次读取时才打开文件，读取完毕后关闭
+type lazyFileReader struct {
+	multipart *StreamMultipart
+	path      string
+	file      *os.File
+}
+
+func (r *lazyFileReader) Read(p []byte) (int, error) {
+	if r.file == nil {
+		file, err := os.Open(r.path)
+		if err != nil {
+			return 0, err
+		}
+		r.file = file
+		r.multipart.file = file
+	}
+	n, err := r.file.Read(p)
+	if err == io.EOF {
+		r.multipart.closeFile()
+	}
+	return n, err
+}
+
+// 可重读的 io.Reader，重读时回到初始位置
+type seekableSection struct {
+	seeker io.ReadSeeker
+	offset int64
+}
+
+func (s *seekableSection) Read(p []byte) (int, error) {
+	return s.seeker.Read(p)
+}
+
+func (s *seekableSection) rewind() error {
+	_, err := s.seeker.Seek(s.offset, io.SeekStart)
+	return err
+}
@@ -0,0 +1,72 @@
+package ghttp
+
+import (
+	"io"
+	"time"
+)
+
+// ProgressFunc 进度回调，current 为已传输的字节数，total 未知时为 -1
+type ProgressFunc func(current, total int64)
+
+// 默认的进度回调间隔
+const defaultProgressInterval = 100 * time.Millisecond
+
+// 进度回调的设置
+type progressOption struct {
+	progress ProgressFunc
+
+	//两次回调的最小间隔，传输结束时总会回调一次
+	interval time.Duration
+}
+
+// 统计读取的字节数，并按间隔回调进度
+type progressReader struct {
+	io.Reader
+	option   *progressOption
+	current  int64
+	total    int64
+	lastTime time.Time
+	done     bool
+}
+
+func newProgressReader(reader io.Reader, total int64, option *progressOption) *progressReader {
+	if total <= 0 {
+		total = -1
+	}
+	return &progressReader{Reader: reader, option: option, total: total}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.current += int64(n)
+
+	if err == io.EOF {
+		if !r.done {
+			r.done = true
+			r.option.progress(r.current, r.total)
+		}
+		return n, err
+	}
+	if n > 0 && time.Since(r.lastTime) >= r.option.interval {
+		r.lastTime = time.Now()
+		r.option.progress(r.current, r.total)
+	}
+	return n, err
+}
+
+// 带进度统计的 io.ReadCloser
+type progressReadCloser struct {
+	*progressReader
+	closer io.Closer
+}
+
+func (r *progressReadCloser) Close() error {
+	return r.closer.Close()
+}
+
+func newProgressReadCloser(rc io.ReadCloser, total int64, option *progressOption) io.ReadCloser {
+	return &progressReadCloser{
+		progressReader: newProgressReader(rc, total, option),
+		closer:         rc,
+	}
+}
@@ -0,0 +1,199 @@
+package ghttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited 触发限流且设置了 FailFast 时返回
+var ErrRateLimited = errors.New("ghttp: rate limited")
+
+// RateLimitKeyFunc 限流的分组函数，同一个key共用一个令牌桶
+type RateLimitKeyFunc func(request *http.Request) string
+
+// RateLimitOptions 限流设置
+type RateLimitOptions struct {
+	//每秒允许的请求数
+	Rate float64
+
+	//令牌桶容量，即允许的突发请求数，<=0 时为1
+	Burst int
+
+	//分组函数，nil 时整个client共用一个令牌桶
+	KeyFunc RateLimitKeyFunc
+
+	//没有可用令牌时立即返回 ErrRateLimited，默认阻塞等待，等待过程中ctx结束时返回ctx的错误，超时可通过 ErrTimeout 判断
+	FailFast bool
+
+	//根据响应头自适应，响应为429或 X-RateLimit-Remaining 为0时，按 Retry-After、X-RateLimit-Reset 暂停该分组的请求
+	Adaptive bool
+
+	//令牌桶超过该时长未使用且令牌已恢复满额时删除，下次请求时重新创建，默认5分钟
+	IdleTimeout time.Duration
+}
+
+// 令牌桶默认的空闲回收时长
+const defaultRateLimitIdleTimeout = 5 * time.Minute
+
+// 令牌桶
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	//自适应限流时，暂停请求直到该时间
+	pausedUntil time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// 获取一个令牌，返回需要等待的时间，为0时已获取到令牌
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	if b.rate <= 0 {
+		return time.Second
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// 等待获取令牌
+func (b *tokenBucket) wait(ctx context.Context, failFast bool) error {
+	for {
+		d := b.reserve()
+		if d == 0 {
+			return nil
+		}
+		if failFast {
+			return ErrRateLimited
+		}
+		if !sleepWithContext(ctx, d) {
+			return classifyError(ctx.Err())
+		}
+	}
+}
+
+// 令牌已满且未暂停，此时删除与新建的令牌桶等价
+func (b *tokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Before(b.pausedUntil) {
+		return false
+	}
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// 暂停请求 d 时长，同时清空令牌
+func (b *tokenBucket) pause(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	b.tokens = 0
+	b.last = until
+}
+
+// 根据响应头调整限流
+func (b *tokenBucket) adapt(resp IResponse) {
+	header := resp.Header()
+	if header == nil {
+		return
+	}
+	if resp.StatusCode() != http.StatusTooManyRequests && header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+
+	if d, ok := retryAfter(resp); ok {
+		b.pause(d)
+		return
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		//较大的值视为unix时间戳，否则视为秒数
+		d := time.Duration(reset) * time.Second
+		if reset > 1e9 {
+			d = time.Until(time.Unix(reset, 0))
+		}
+		if d > 0 {
+			b.pause(d)
+		}
+	}
+}
+
+// 按分组管理令牌桶
+type rateLimiter struct {
+	options RateLimitOptions
+
+	buckets *keyedGroups[*tokenBucket]
+}
+
+func newRateLimiter(options RateLimitOptions) *rateLimiter {
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = defaultRateLimitIdleTimeout
+	}
+	return &rateLimiter{
+		options: options,
+		buckets: newKeyedGroups(options.IdleTimeout, func(string) *tokenBucket {
+			return newTokenBucket(options.Rate, options.Burst)
+		}, (*tokenBucket).full),
+	}
+}
+
+func (l *rateLimiter) bucket(request *http.Request) *tokenBucket {
+	key := ""
+	if l.options.KeyFunc != nil {
+		key = l.options.KeyFunc(request)
+	}
+
+	return l.buckets.get(key)
+}
+
+// 内置中间件：限流，每次尝试（含重试）都需要获取令牌
+func (c *client) rateLimitMiddleware(next Handler) Handler {
+	return func(ctx context.Context, request *http.Request) IResponse {
+		bucket := c.rateLimiter.bucket(request)
+		if err := bucket.wait(ctx, c.rateLimiter.options.FailFast); err != nil {
+			return c.errorResponse(ctx, err)
+		}
+
+		resp := next(ctx, request)
+		if c.rateLimiter.options.Adaptive {
+			bucket.adapt(resp)
+		}
+		return resp
+	}
+}
@@ -0,0 +1,258 @@
+package ghttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 脱敏后的值
+const redactedMask = "[REDACTED]"
+
+// DefaultRedactHeaders 默认脱敏的请求头
+func DefaultRedactHeaders() []string {
+	return []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Api-Key",
+		"X-Auth-Token",
+	}
+}
+
+// Redactor 自定义脱敏，在内置脱敏之后对日志的每个属性调用，返回替换后的属性
+// 属性包括 method、url、status、request_header、request_body、response_body 等
+type Redactor func(attr slog.Attr) slog.Attr
+
+// 日志脱敏规则
+type redactor struct {
+	//请求头，CanonicalHeaderKey 格式
+	headers map[string]struct{}
+
+	//json字段路径，按 . 拆分
+	jsonPaths [][]string
+
+	//form表单的key
+	formKeys []string
+
+	//url中的query参数
+	queryParams []string
+
+	//自定义脱敏
+	custom Redactor
+}
+
+func newRedactor() *redactor {
+	r := &redactor{headers: make(map[string]struct{})}
+	r.addHeaders(DefaultRedactHeaders()...)
+	return r
+}
+
+func (r *redactor) addHeaders(names ...string) {
+	for _, name := range names {
+		r.headers[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+}
+
+func (r *redactor) addJsonPaths(paths ...string) {
+	for _, path := range paths {
+		r.jsonPaths = append(r.jsonPaths, strings.Split(path, "."))
+	}
+}
+
+func (r *redactor) clone() *redactor {
+	c := *r
+	c.headers = make(map[string]struct{}, len(r.headers))
+	for k := range r.headers {
+		c.headers[k] = struct{}{}
+	}
+	c.jsonPaths = append([][]string(nil), r.jsonPaths...)
+	c.formKeys = append([]string(nil), r.formKeys...)
+	c.queryParams = append([]string(nil), r.queryParams...)
+	return &c
+}
+
+// 脱敏url中的密码及query参数
+func (r *redactor) url(u *url.URL) string {
+	masked := *u
+	if len(r.queryParams) != 0 && masked.RawQuery != "" {
+		masked.RawQuery = redactQuery(masked.RawQuery, r.queryParams)
+	}
+	return masked.Redacted()
+}
+
+// 脱敏字符串格式的url，无法解析时原样返回
+func (r *redactor) rawURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return r.url(u)
+}
+
+// 脱敏错误信息中的url，如 *url.Error 的 Get "http://h/x?token=...": ... 及 *HTTPError
+func (r *redactor) errorText(err error) string {
+	text := err.Error()
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.URL != "" {
+		text = strings.ReplaceAll(text, urlErr.URL, r.rawURL(urlErr.URL))
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.URL != "" {
+		text = strings.ReplaceAll(text, httpErr.URL, r.rawURL(httpErr.URL))
+	}
+	return text
+}
+
+// 脱敏请求头，返回副本，Referer 等携带url的请求头按url脱敏
+func (r *redactor) header(header http.Header) http.Header {
+	masked := header.Clone()
+	for k, values := range masked {
+		key := http.CanonicalHeaderKey(k)
+		if _, ok := r.headers[key]; ok {
+			masked[k] = []string{redactedMask}
+			continue
+		}
+		if _, ok := urlHeaders[key]; ok {
+			for i, v := range values {
+				values[i] = r.rawURL(v)
+			}
+		}
+	}
+	return masked
+}
+
+// 值为url的请求头
+var urlHeaders = map[string]struct{}{
+	"Referer":  {},
+	"Origin":   {},
+	"Location": {},
+}
+
+// 按 Content-Type 脱敏body，json按字段路径，form按key，其他类型不处理
+// 未设置 Content-Type 或为 text/* 时，内容以 { 或 [ 开头的按json处理
+func (r *redactor) body(contentType, body string) string {
+	if body == "" {
+		return body
+	}
+	media := mediaType(contentType)
+	switch {
+	case len(r.jsonPaths) != 0 && (media == HTTP_CONTENT_TYPE_JSON || strings.HasSuffix(media, "+json") || looksLikeJson(media, body)):
+		return redactJson(body, r.jsonPaths)
+	case len(r.formKeys) != 0 && media == HTTP_CONTENT_TYPE_FROM_DATA:
+		return redactQuery(body, r.formKeys)
+	}
+	return body
+}
+
+// Content-Type 未声明为json，但内容可能是json
+func looksLikeJson(media, body string) bool {
+	if media != "" && !strings.HasPrefix(media, "text/") {
+		return false
+	}
+	body = strings.TrimSpace(body)
+	return strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[")
+}
+
+// 对日志属性执行自定义脱敏
+func (r *redactor) apply(attrs []slog.Attr) []slog.Attr {
+	if r.custom == nil {
+		return attrs
+	}
+	for i, attr := range attrs {
+		attrs[i] = r.custom(attr)
+	}
+	return attrs
+}
+
+// 脱敏 a=1&b=2 格式中指定key的值，key 不区分大小写，保留原有顺序
+func redactQuery(raw string, keys []string) string {
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		for _, k := range keys {
+			if strings.EqualFold(key, k) {
+				pairs[i] = rawKey + "=" + redactedMask
+				break
+			}
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// 脱敏json中指定路径的字段，无法解析时原样返回
+// 路径以 . 分隔，如 user.token，从根节点开始匹配，* 匹配任意字段名，数组的元素逐个匹配；
+// 不含 . 的路径如 password 匹配任意层级的同名字段
+func redactJson(body string, paths [][]string) string {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return body
+	}
+	for _, path := range paths {
+		if len(path) == 1 {
+			v = redactJsonKey(v, path[0])
+		} else {
+			v = redactJsonPath(v, path)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return body
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// 按路径脱敏
+func redactJsonPath(v interface{}, path []string) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if path[0] != "*" && path[0] != k {
+				continue
+			}
+			if len(path) == 1 {
+				value[k] = redactedMask
+			} else {
+				value[k] = redactJsonPath(child, path[1:])
+			}
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = redactJsonPath(child, path)
+		}
+	}
+	return v
+}
+
+// 脱敏任意层级的同名字段
+func redactJsonKey(v interface{}, key string) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if key == "*" || k == key {
+				value[k] = redactedMask
+			} else {
+				value[k] = redactJsonKey(child, key)
+			}
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = redactJsonKey(child, key)
+		}
+	}
+	return v
+}
@@ -0,0 +1,514 @@
+package ghttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Request 单次请求的构造器，通过 client.R() 获得
+// header、cookie、query参数、body、ctx及超时时间均只对本次请求生效，多个goroutine共用同一个client时互不影响
+// Request 本身并发不安全，不要在多个goroutine之间共享同一个 Request
+type Request struct {
+	client *client
+
+	//请求上下文，默认 context.Background()
+	ctx context.Context
+
+	//本次请求的超时时间，0表示不单独设置，以client的超时时间为准
+	timeout time.Duration
+
+	//本次请求的header，覆盖client的全局header
+	header map[string]string
+
+	//本次请求的cookie，追加在client的全局cookie之后
+	cookies []*http.Cookie
+
+	//本次请求的query参数，与url中已有的query合并
+	query url.Values
+
+	//路径参数，替换url中的 {name}
+	pathParams map[string]string
+
+	//请求body
+	body io.Reader
+
+	//用于日志记录的请求参数
+	logBody string
+
+	//本次请求日志中的uniqueId，为空时使用client的设置
+	uniqueId string
+
+	//本次请求是否记录请求、响应body，nil 时以client的设置为准
+	logRequestBody  *bool
+	logResponseBody *bool
+
+	//设置 Content-Type 的函数
+	setContentType ContentTypeFunc
+
+	//本次请求的重试策略，nil 时使用client的重试策略
+	retry *retryPolicy
+
+	//是否以流式响应返回
+	stream bool
+
+	//上传、下载进度回调
+	uploadProgress   *progressOption
+	downloadProgress *progressOption
+
+	//构造body时产生的错误，在发起请求时返回
+	err error
+}
+
+// R 生成一个新的 Request，用于构造单次请求
+func (c *client) R() *Request {
+	return &Request{
+		client: c,
+		ctx:    context.Background(),
+	}
+}
+
+// SetContext 设置本次请求的ctx，ctx 取消或超时时，请求随之中断
+func (r *Request) SetContext(ctx context.Context) *Request {
+	r.ctx = withDefaultContext(ctx)
+	return r
+}
+
+// SetTimeout 设置本次请求的超时时间
+func (r *Request) SetTimeout(t time.Duration) *Request {
+	r.timeout = t
+	return r
+}
+
+// SetHeader 设置本次请求的单个header
+func (r *Request) SetHeader(key, value string) *Request {
+	if r.header == nil {
+		r.header = make(map[string]string)
+	}
+	r.header[key] = value
+	return r
+}
+
+// SetHeaders 批量设置本次请求的header
+func (r *Request) SetHeaders(header map[string]string) *Request {
+	for k, v := range header {
+		r.SetHeader(k, v)
+	}
+	return r
+}
+
+// SetCookie 追加本次请求的单个cookie
+func (r *Request) SetCookie(cookie *http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
+// SetCookies 批量追加本次请求的cookie
+func (r *Request) SetCookies(cookies []*http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookies...)
+	return r
+}
+
+// SetQueryParam 设置单个query参数，同名参数会被覆盖
+func (r *Request) SetQueryParam(key, value string) *Request {
+	if r.query == nil {
+		r.query = make(url.Values)
+	}
+	r.query.Set(key, value)
+	return r
+}
+
+// SetQueryParams 批量设置query参数
+func (r *Request) SetQueryParams(params map[string]string) *Request {
+	for k, v := range params {
+		r.SetQueryParam(k, v)
+	}
+	return r
+}
+
+// SetQueryValues 批量设置query参数，支持一个key对应多个值
+func (r *Request) SetQueryValues(values url.Values) *Request {
+	if r.query == nil {
+		r.query = make(url.Values)
+	}
+	for k, v := range values {
+		r.query[k] = v
+	}
+	return r
+}
+
+// SetQueryStruct 将带 `query` tag 的struct编码为query参数，编码规则见 ValuesEncoder
+func (r *Request) SetQueryStruct(v interface{}) *Request {
+	values, err := queryEncoder.Encode(v)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.SetQueryValues(values)
+}
+
+// SetPathParam 设置路径参数，替换url中的 {name}，值会进行路径转义
+// 如 client.R().SetPathParam("id", "1").Get("/users/{id}")
+func (r *Request) SetPathParam(name, value string) *Request {
+	if r.pathParams == nil {
+		r.pathParams = make(map[string]string)
+	}
+	r.pathParams[name] = value
+	return r
+}
+
+// SetPathParams 批量设置路径参数
+func (r *Request) SetPathParams(params map[string]string) *Request {
+	for k, v := range params {
+		r.SetPathParam(k, v)
+	}
+	return r
+}
+
+// SetContentType 设置本次请求的 Content-Type
+func (r *Request) SetContentType(contentType string) *Request {
+	r.setContentType = func(request *http.Request) {
+		request.Header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+// SetBody 设置原始请求body，body内容不记录日志
+func (r *Request) SetBody(body io.Reader) *Request {
+	r.body = body
+	return r
+}
+
+// SetBodyBytes 设置[]byte请求body
+func (r *Request) SetBodyBytes(body []byte) *Request {
+	r.body = bytes.NewReader(body)
+	r.logBody = string(body)
+	return r
+}
+
+// SetFormData 设置form请求body，Content-Type 为 application/x-www-form-urlencoded
+func (r *Request) SetFormData(values url.Values) *Request {
+	encoded := values.Encode()
+	r.body = strings.NewReader(encoded)
+	r.logBody = encoded
+	r.setContentType = setRequestPostFrom
+	return r
+}
+
+// SetJsonBody 设置json请求body
+func (r *Request) SetJsonBody(value interface{}) *Request {
+	return r.SetCodecBody(HTTP_CONTENT_TYPE_JSON, value)
+}
+
+// SetXmlBody 设置xml请求body
+func (r *Request) SetXmlBody(value interface{}) *Request {
+	return r.SetCodecBody(HTTP_CONTENT_TYPE_XML, value)
+}
+
+// SetCodecBody 使用 contentType 对应的编解码器编码请求body，并设置 Content-Type
+func (r *Request) SetCodecBody(contentType string, value interface{}) *Request {
+	if value == nil {
+		r.err = fmt.Errorf("%s body is nil", contentType)
+		return r
+	}
+	codec, err := r.client.codec(contentType)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	by, err := codec.Marshal(value)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.SetBodyBytes(by).SetContentType(codec.ContentType())
+}
+
+// SetBodyEncoder 使用 Body 编码器设置请求body，body 为 nil 时不设置
+func (r *Request) SetBodyEncoder(body Body) *Request {
+	if body != nil {
+		body.Apply(r)
+	}
+	return r
+}
+
+// SetMultipart 设置multipart请求body
+func (r *Request) SetMultipart(body IMultipart) *Request {
+	r.body = body
+	r.setContentType = func(request *http.Request) {
+		request.Header.Set("Content-Type", body.ContentType())
+	}
+	return r
+}
+
+// SetStream 本次请求以流式响应返回，响应内容不缓存，由调用方通过 IResponse.Body() 读取并 Close
+func (r *Request) SetStream() *Request {
+	r.stream = true
+	return r
+}
+
+// SetUniqueId 设置本次请求日志中的uniqueId，覆盖client的设置
+func (r *Request) SetUniqueId(id string) *Request {
+	r.uniqueId = id
+	return r
+}
+
+// SetLogRequestBody 设置本次请求是否在日志中记录请求body，覆盖client的设置
+func (r *Request) SetLogRequestBody(enabled bool) *Request {
+	r.logRequestBody = &enabled
+	return r
+}
+
+// SetLogResponseBody 设置本次请求是否在日志中记录响应body，覆盖client的设置
+func (r *Request) SetLogResponseBody(enabled bool) *Request {
+	r.logResponseBody = &enabled
+	return r
+}
+
+// SetUploadProgress 设置上传进度回调，interval 为两次回调的最小间隔，<=0 时使用默认的100ms
+func (r *Request) SetUploadProgress(progress ProgressFunc, interval time.Duration) *Request {
+	r.uploadProgress = newProgressOption(progress, interval)
+	return r
+}
+
+// SetDownloadProgress 设置下载进度回调，interval 为两次回调的最小间隔，<=0 时使用默认的100ms
+// 流式响应在调用方读取 Body() 时回调
+func (r *Request) SetDownloadProgress(progress ProgressFunc, interval time.Duration) *Request {
+	r.downloadProgress = newProgressOption(progress, interval)
+	return r
+}
+
+func newProgressOption(progress ProgressFunc, interval time.Duration) *progressOption {
+	if progress == nil {
+		return nil
+	}
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	return &progressOption{progress: progress, interval: interval}
+}
+
+// SetRetry 设置本次请求的失败重试，覆盖client的重试次数及退避函数，count 为0时本次请求不重试
+func (r *Request) SetRetry(count int, backoff Backoff) *Request {
+	r.retryPolicy().count = count
+	r.retry.backoff = backoff
+	return r
+}
+
+// SetRetryCondition 设置本次请求的重试条件
+func (r *Request) SetRetryCondition(condition RetryCondition) *Request {
+	r.retryPolicy().condition = condition
+	return r
+}
+
+// SetRetryMaxWait 设置本次请求单次重试前的最长等待时间，响应的 Retry-After 超出时不再重试
+func (r *Request) SetRetryMaxWait(maxWait time.Duration) *Request {
+	r.retryPolicy().maxWait = maxWait
+	return r
+}
+
+// 获取本次请求的重试策略，首次修改时复制client的重试策略
+func (r *Request) retryPolicy() *retryPolicy {
+	if r.retry == nil {
+		r.retry = &retryPolicy{}
+		if r.client.retry != nil {
+			*r.retry = *r.client.retry
+		}
+	}
+	return r.retry
+}
+
+// Get 发起GET请求
+func (r *Request) Get(url string) IResponse {
+	return r.Execute(http.MethodGet, url)
+}
+
+// Post 发起POST请求
+func (r *Request) Post(url string) IResponse {
+	return r.Execute(http.MethodPost, url)
+}
+
+// Put 发起PUT请求
+func (r *Request) Put(url string) IResponse {
+	return r.Execute(http.MethodPut, url)
+}
+
+// Patch 发起PATCH请求
+func (r *Request) Patch(url string) IResponse {
+	return r.Execute(http.MethodPatch, url)
+}
+
+// Delete 发起DELETE请求
+func (r *Request) Delete(url string) IResponse {
+	return r.Execute(http.MethodDelete, url)
+}
+
+// Head 发起HEAD请求
+func (r *Request) Head(url string) IResponse {
+	return r.Execute(http.MethodHead, url)
+}
+
+// Options 发起OPTIONS请求
+func (r *Request) Options(url string) IResponse {
+	return r.Execute(http.MethodOptions, url)
+}
+
+// Execute 以指定的method发起请求
+func (r *Request) Execute(method, url string) IResponse {
+	c := r.client
+	ctx, cancel := r.context()
+
+	request, err := r.httpRequest(ctx, method, url)
+	if err != nil {
+		cancel()
+		return c.errorResponse(ctx, err)
+	}
+	return releaseOnClose(r.send(ctx, request), cancel)
+}
+
+// ExecuteAsync 以指定的method发起异步请求，使用回调函数
+// 请求由 client 的异步执行器调度，执行器已满或已关闭时返回错误，此时回调不会被调用
+func (r *Request) ExecuteAsync(method, url string, call func(response IResponse)) error {
+	if call == nil {
+		return errors.New("callback function is nil")
+	}
+
+	ctx, cancel := r.context()
+	request, err := r.httpRequest(ctx, method, url)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	err = r.client.executor.submit(ctx, &asyncTask{
+		run: func() {
+			call(releaseOnClose(r.send(ctx, request), cancel))
+		},
+		drop: func(err error) {
+			call(r.client.errorResponse(ctx, err))
+			cancel()
+		},
+	})
+	if err != nil {
+		cancel()
+	}
+	return err
+}
+
+// 发送请求，按重试策略重试，每次尝试均记录日志
+func (r *Request) send(ctx context.Context, request *http.Request) IResponse {
+	c := r.client
+	policy := r.retry
+	if policy == nil {
+		policy = c.retry
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp := c.handler(context.WithValue(ctx, ctxKeyAttempt, attempt), request)
+		if !policy.shouldRetry(attempt, resp) {
+			return resp
+		}
+		next, ok := rewindRequest(request)
+		if !ok {
+			return resp
+		}
+		wait, ok := policy.wait(attempt, resp)
+		if !ok || !sleepWithContext(ctx, wait) {
+			return resp
+		}
+		closeResponse(resp)
+		request = next
+	}
+}
+
+// 生成本次请求的ctx，设置了超时时间时附加超时控制
+func (r *Request) context() (context.Context, context.CancelFunc) {
+	ctx := withDefaultContext(r.ctx)
+	if r.stream {
+		ctx = context.WithValue(ctx, ctxKeyStream, true)
+	}
+	if r.downloadProgress != nil {
+		ctx = context.WithValue(ctx, ctxKeyDownloadProgress, r.downloadProgress)
+	}
+	if r.logBody != "" {
+		ctx = r.client.buildContext(ctx, r.logBody)
+	}
+	if r.uniqueId != "" {
+		ctx = context.WithValue(ctx, ctxKeyUniqueId, r.uniqueId)
+	}
+	if r.logRequestBody != nil {
+		ctx = context.WithValue(ctx, ctxKeyLogRequestBody, *r.logRequestBody)
+	}
+	if r.logResponseBody != nil {
+		ctx = context.WithValue(ctx, ctxKeyLogResponseBody, *r.logResponseBody)
+	}
+	if r.timeout > 0 {
+		return context.WithTimeout(ctx, r.timeout)
+	}
+	return ctx, func() {}
+}
+
+// 生成 *http.Request，依次替换路径参数、拼接基础地址、合并query参数
+func (r *Request) httpRequest(ctx context.Context, method, rawUrl string) (*http.Request, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var err error
+	if r.pathParams != nil {
+		if rawUrl, err = GPath(rawUrl, r.pathParams); err != nil {
+			return nil, err
+		}
+	}
+	if rawUrl, err = resolveURL(r.client.baseURL, rawUrl); err != nil {
+		return nil, err
+	}
+
+	if len(r.query) != 0 {
+		u, err := url.Parse(rawUrl)
+		if err != nil {
+			return nil, err
+		}
+		query := u.Query()
+		for k, v := range r.query {
+			query[k] = v
+		}
+		u.RawQuery = query.Encode()
+		rawUrl = u.String()
+	}
+
+	request, err := r.client.getRequest(ctx, method, rawUrl, r.body, r.header, r.cookies)
+	if err != nil {
+		return nil, err
+	}
+	if r.setContentType != nil {
+		r.setContentType(request)
+	}
+	if r.uploadProgress != nil {
+		setUploadProgress(request, r.uploadProgress)
+	}
+	return request, nil
+}
+
+// 为请求body添加上传进度统计，重试时重新计数
+func setUploadProgress(request *http.Request, option *progressOption) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return
+	}
+	request.Body = newProgressReadCloser(request.Body, request.ContentLength, option)
+	if getBody := request.GetBody; getBody != nil {
+		request.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return newProgressReadCloser(body, request.ContentLength, option), nil
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package ghttp
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 )
@@ -32,12 +35,50 @@ type IResponse interface {
 
 	// Cookie 根据名称返回cookie值
 	Cookie(name string) *http.Cookie
+
+	// Text 以字符串返回HTTP内容
+	Text() string
+
+	// JSON 将HTTP内容以json解析到v
+	JSON(v interface{}) error
+
+	// XML 将HTTP内容以xml解析到v
+	XML(v interface{}) error
+
+	// Unmarshal 根据响应的 Content-Type 选择解析方式，将HTTP内容解析到v
+	Unmarshal(v interface{}) error
+
+	// Body 返回HTTP内容的读取流
+	// 流式响应返回的是未读取的原始body，调用方负责读取并 Close；非流式响应返回已缓存内容的读取流
+	Body() io.ReadCloser
+
+	// Streamed 是否为流式响应，流式响应不缓存内容，Content() 返回nil
+	Streamed() bool
 }
 
 type HttpResponse struct {
 	err             error
 	ResponseContent []byte
 	httpResp        *http.Response
+
+	//是否为流式响应，流式响应不缓存内容，由调用方通过 Body() 读取
+	stream bool
+	body   io.ReadCloser
+
+	//解析响应内容使用的编解码器，nil 时使用内置的编解码器
+	codecs codecRegistry
+
+	//构造 HTTPError 时脱敏url使用的规则，nil 时只脱敏url中的密码
+	redactor *redactor
+}
+
+// NewHttpResponse 构造一个响应，可用于中间件中直接返回响应（如缓存、mock）
+func NewHttpResponse(resp *http.Response, content []byte, err error) *HttpResponse {
+	return &HttpResponse{
+		err:             err,
+		ResponseContent: content,
+		httpResp:        resp,
+	}
 }
 
 func (h *HttpResponse) Error() error {
@@ -89,6 +130,94 @@ func (h *HttpResponse) Cookie(name string) *http.Cookie {
 	return nil
 }
 
+func (h *HttpResponse) Body() io.ReadCloser {
+	if h.stream {
+		return h.body
+	}
+	return io.NopCloser(bytes.NewReader(h.ResponseContent))
+}
+
+// Streamed 是否为流式响应
+func (h *HttpResponse) Streamed() bool {
+	return h.stream
+}
+
+func (h *HttpResponse) Text() string {
+	return string(h.ResponseContent)
+}
+
+func (h *HttpResponse) JSON(v interface{}) error {
+	return h.decode(HTTP_CONTENT_TYPE_JSON, v)
+}
+
+func (h *HttpResponse) XML(v interface{}) error {
+	return h.decode(HTTP_CONTENT_TYPE_XML, v)
+}
+
+// Unmarshal 根据 Content-Type 查找已注册的编解码器，未找到时根据内容首字符判断json、xml
+// 流式响应只根据 Content-Type 判断
+func (h *HttpResponse) Unmarshal(v interface{}) error {
+	if h.err != nil {
+		return h.err
+	}
+
+	contentType := h.Header().Get("Content-Type")
+	if _, ok := h.codecs.lookup(contentType); ok {
+		return h.decode(contentType, v)
+	}
+	if h.stream {
+		return fmt.Errorf("unsupported stream response content type: %q", contentType)
+	}
+
+	content := bytes.TrimSpace(h.ResponseContent)
+	if len(content) == 0 {
+		return errors.New("response content is empty")
+	}
+	switch content[0] {
+	case '{', '[':
+		return h.JSON(v)
+	case '<':
+		return h.XML(v)
+	}
+	return fmt.Errorf("unsupported response content type: %q", contentType)
+}
+
+// 使用 contentType 对应的编解码器解析响应内容，流式响应读取完毕后关闭body
+func (h *HttpResponse) decode(contentType string, v interface{}) error {
+	if h.err != nil {
+		return h.err
+	}
+	codec, ok := h.codecs.lookup(contentType)
+	if !ok {
+		return fmt.Errorf("ghttp: no codec registered for %q", contentType)
+	}
+
+	content := h.ResponseContent
+	if h.stream {
+		defer h.body.Close()
+		var err error
+		if content, err = io.ReadAll(h.body); err != nil {
+			return err
+		}
+	}
+	return codec.Unmarshal(content, v)
+}
+
+// Decode 将响应解析为T，请求错误、非2xx状态码（*HTTPError）及解析错误均以error返回
+func Decode[T any](resp IResponse) (T, error) {
+	var v T
+	if err := resp.Error(); err != nil {
+		return v, err
+	}
+	if code := resp.StatusCode(); code < 200 || code > 299 {
+		return v, newHTTPError(resp)
+	}
+	if err := resp.Unmarshal(&v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
 // DefaultBuildResponse 默认的HTTP响应构造器
 func DefaultBuildResponse(ctx context.Context, resp *http.Response, err error) (context.Context, IResponse) {
 	iResponse := new(HttpResponse)
@@ -108,3 +237,17 @@ func DefaultBuildResponse(ctx context.Context, resp *http.Response, err error) (
 
 	return ctx, iResponse
 }
+
+// StreamBuildResponse 流式的HTTP响应构造器，不读取body，由调用方通过 IResponse.Body() 读取并 Close
+// 适用于下载大文件等场景，日志中不记录响应内容
+func StreamBuildResponse(ctx context.Context, resp *http.Response, err error) (context.Context, IResponse) {
+	iResponse := &HttpResponse{stream: true}
+	if err != nil {
+		iResponse.err = err
+		return ctx, iResponse
+	}
+
+	iResponse.httpResp = resp
+	iResponse.body = resp.Body
+	return ctx, iResponse
+}
@@ -0,0 +1,158 @@
+package ghttp
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Backoff 计算第 attempt 次重试前的等待时间，attempt 从1开始
+type Backoff func(attempt int) time.Duration
+
+// RetryCondition 根据本次响应判断是否需要重试
+type RetryCondition func(resp IResponse) bool
+
+// 默认的退避区间
+const (
+	defaultRetryBaseWait = 100 * time.Millisecond
+	defaultRetryMaxWait  = 10 * time.Second
+
+	//Retry-After 的默认上限
+	defaultRetryAfterMaxWait = time.Minute
+)
+
+// ExponentialBackoff 带抖动的指数退避，等待时间为 base*2^(attempt-1)，最大不超过 max
+// 实际等待时间在 [d/2, d] 之间随机，避免大量请求同时重试
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		if d <= 0 {
+			return 0
+		}
+		half := d / 2
+		return half + time.Duration(rand.Int63n(int64(d-half)+1))
+	}
+}
+
+// DefaultRetryCondition 默认的重试条件
+// 传输错误（ctx取消、超时、熔断、限流除外）、429 及 5xx（501除外）时重试
+func DefaultRetryCondition(resp IResponse) bool {
+	var httpErr *HTTPError
+	if err := resp.Error(); err != nil && !errors.As(err, &httpErr) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, ErrRateLimited)
+	}
+	code := resp.StatusCode()
+	return code == http.StatusTooManyRequests || (code >= 500 && code != http.StatusNotImplemented)
+}
+
+// 重试策略
+type retryPolicy struct {
+	//最大重试次数，不包含首次请求
+	count int
+
+	//退避函数
+	backoff Backoff
+
+	//重试条件
+	condition RetryCondition
+
+	//单次重试前的最长等待时间，Retry-After 超出时不再重试，<=0 时使用默认的1分钟
+	maxWait time.Duration
+}
+
+// 是否需要第 attempt 次重试
+func (p *retryPolicy) shouldRetry(attempt int, resp IResponse) bool {
+	if p == nil || attempt > p.count {
+		return false
+	}
+	condition := p.condition
+	if condition == nil {
+		condition = DefaultRetryCondition
+	}
+	return condition(resp)
+}
+
+// 第 attempt 次重试前的等待时间，响应携带 Retry-After 时以其为准
+// Retry-After 超出 maxWait 时返回 false，不再重试
+func (p *retryPolicy) wait(attempt int, resp IResponse) (time.Duration, bool) {
+	maxWait := p.maxWait
+	if maxWait <= 0 {
+		maxWait = defaultRetryAfterMaxWait
+	}
+	if d, ok := retryAfter(resp); ok {
+		return d, d <= maxWait
+	}
+	backoff := p.backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(defaultRetryBaseWait, defaultRetryMaxWait)
+	}
+	d := backoff(attempt)
+	if d > maxWait {
+		d = maxWait
+	}
+	return d, true
+}
+
+// 解析 Retry-After 响应头，支持秒数及HTTP日期两种格式
+func retryAfter(resp IResponse) (time.Duration, bool) {
+	header := resp.Header()
+	if header == nil {
+		return 0, false
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// 等待 d 时长，ctx 结束时提前返回 false
+func sleepWithContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// 重建一个可重新发送的 request，body 无法重读时返回 false
+func rewindRequest(request *http.Request) (*http.Request, bool) {
+	next := request.Clone(request.Context())
+	if request.Body == nil || request.Body == http.NoBody {
+		return next, true
+	}
+	if request.GetBody == nil {
+		return nil, false
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	next.Body = body
+	return next, true
+}
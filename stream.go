@@ -0,0 +1,57 @@
+package ghttp
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// 关闭响应的body，用于丢弃的响应（如重试前），非流式响应的body已读取完毕，关闭无副作用
+func closeResponse(resp IResponse) {
+	if body := resp.Body(); body != nil {
+		_ = body.Close()
+	}
+}
+
+// 流式响应在body关闭时才释放ctx，避免ctx提前取消导致body无法读取
+type cancelOnCloseResponse struct {
+	IResponse
+	body io.ReadCloser
+}
+
+func (r *cancelOnCloseResponse) Body() io.ReadCloser {
+	return r.body
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	once   sync.Once
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.cancel)
+	return err
+}
+
+// 将ctx的释放延迟到流式响应的body关闭时，非流式响应立即释放
+func releaseOnClose(resp IResponse, cancel context.CancelFunc) IResponse {
+	if !resp.Streamed() || resp.Body() == nil {
+		cancel()
+		return resp
+	}
+
+	switch r := resp.(type) {
+	case *HttpResponse:
+		r.body = &cancelOnCloseBody{ReadCloser: r.body, cancel: cancel}
+		return r
+	case *statusErrorResponse:
+		r.IResponse = releaseOnClose(r.IResponse, cancel)
+		return r
+	}
+	return &cancelOnCloseResponse{
+		IResponse: resp,
+		body:      &cancelOnCloseBody{ReadCloser: resp.Body(), cancel: cancel},
+	}
+}
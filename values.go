@@ -0,0 +1,307 @@
+package ghttp
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArrayStyle 切片、数组字段的编码方式
+type ArrayStyle int8
+
+// ArrayStyle 的枚举
+const (
+	//a=1&a=2
+	ArrayRepeat ArrayStyle = iota
+
+	//a[]=1&a[]=2
+	ArrayBrackets
+
+	//a=1,2
+	ArrayComma
+)
+
+// ValuesEncoder 将带tag的struct编码为 url.Values，用于query参数及form表单
+//
+// tag 格式为 `query:"name,omitempty,brackets"`，支持的选项：
+//   - omitempty 零值时忽略该字段
+//   - repeat、brackets、comma 覆盖切片的编码方式
+//   - unix 时间以秒级时间戳编码
+//
+// 时间字段可通过 `layout:"2006-01-02"` 设置格式；嵌套的struct、map以 parent[child] 的形式编码，
+// 匿名嵌入且未设置tag的struct展开到上一层；nil指针忽略
+type ValuesEncoder struct {
+	//读取的tag名，如 query、form
+	Tag string
+
+	//切片的默认编码方式
+	ArrayStyle ArrayStyle
+
+	//时间的默认格式，为空时使用 time.RFC3339
+	TimeLayout string
+}
+
+// 字段的tag选项
+type valuesTagOptions struct {
+	omitEmpty  bool
+	unix       bool
+	arrayStyle ArrayStyle
+	layout     string
+}
+
+// Encode 将v编码为 url.Values，v 需为struct或struct指针，url.Values 及 map 直接转换
+func (e *ValuesEncoder) Encode(v interface{}) (url.Values, error) {
+	switch value := v.(type) {
+	case url.Values:
+		return value, nil
+	case map[string][]string:
+		return value, nil
+	case map[string]string:
+		return GPostData(value), nil
+	}
+
+	values := make(url.Values)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return values, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ghttp: values encoder unsupported type %T", v)
+	}
+	if err := e.encodeStruct(values, "", rv); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (e *ValuesEncoder) encodeStruct(values url.Values, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, hasTag := field.Tag.Lookup(e.Tag)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "-" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		//匿名嵌入且未设置tag的struct，展开到上一层
+		if field.Anonymous && !hasTag {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := e.encodeStruct(values, prefix, fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if prefix != "" {
+			name = prefix + "[" + name + "]"
+		}
+
+		opts := valuesTagOptions{arrayStyle: e.ArrayStyle, layout: field.Tag.Get("layout")}
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "omitempty":
+				opts.omitEmpty = true
+			case "unix":
+				opts.unix = true
+			case "repeat":
+				opts.arrayStyle = ArrayRepeat
+			case "brackets":
+				opts.arrayStyle = ArrayBrackets
+			case "comma":
+				opts.arrayStyle = ArrayComma
+			}
+		}
+		if opts.layout == "" {
+			opts.layout = e.TimeLayout
+		}
+
+		if err := e.encodeValue(values, name, fv, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ValuesEncoder) encodeValue(values url.Values, name string, fv reflect.Value, opts valuesTagOptions) error {
+	if opts.omitEmpty && isEmptyValue(fv) {
+		return nil
+	}
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+
+	if s, ok, err := formatScalar(fv, opts); ok || err != nil {
+		if err == nil {
+			values.Add(name, s)
+		}
+		return err
+	}
+
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(name, string(fv.Bytes()))
+			return nil
+		}
+		items := make([]string, 0, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			item := fv.Index(i)
+			for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+				if item.IsNil() {
+					break
+				}
+				item = item.Elem()
+			}
+			//nil元素忽略
+			if (item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface) && item.IsNil() {
+				continue
+			}
+			s, ok, err := formatScalar(item, opts)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("ghttp: values encoder unsupported element type %s of %s", item.Type(), name)
+			}
+			items = append(items, s)
+		}
+		switch opts.arrayStyle {
+		case ArrayComma:
+			if len(items) != 0 {
+				values.Add(name, strings.Join(items, ","))
+			}
+		case ArrayBrackets:
+			values[name+"[]"] = append(values[name+"[]"], items...)
+		default:
+			values[name] = append(values[name], items...)
+		}
+	case reflect.Struct:
+		return e.encodeStruct(values, name, fv)
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("ghttp: values encoder unsupported map key type %s of %s", fv.Type().Key(), name)
+		}
+		keys := fv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			if err := e.encodeValue(values, name+"["+key.String()+"]", fv.MapIndex(key), valuesTagOptions{arrayStyle: opts.arrayStyle, layout: opts.layout, unix: opts.unix}); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("ghttp: values encoder unsupported type %s of %s", fv.Type(), name)
+	}
+	return nil
+}
+
+// 格式化单个值，不是单值类型时返回 false
+func formatScalar(fv reflect.Value, opts valuesTagOptions) (string, bool, error) {
+	if !fv.IsValid() {
+		return "", true, nil
+	}
+	if (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) && fv.IsNil() {
+		return "", true, nil
+	}
+	if !fv.CanInterface() {
+		return formatKind(fv)
+	}
+	if t, ok := fv.Interface().(time.Time); ok {
+		if opts.unix {
+			return strconv.FormatInt(t.Unix(), 10), true, nil
+		}
+		layout := opts.layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return t.Format(layout), true, nil
+	}
+	if marshaler, ok := fv.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), true, err
+	}
+	return formatKind(fv)
+}
+
+// 按基础类型格式化
+func formatKind(fv reflect.Value) (string, bool, error) {
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(fv.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'f', -1, fv.Type().Bits()), true, nil
+	}
+	return "", false, nil
+}
+
+// 判断是否为零值，用于 omitempty
+func isEmptyValue(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return fv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return fv.IsNil()
+	}
+	if fv.CanInterface() {
+		if t, ok := fv.Interface().(time.Time); ok {
+			return t.IsZero()
+		}
+	}
+	return fv.IsZero()
+}
+
+// 默认的query、form编码器
+var (
+	queryEncoder = &ValuesEncoder{Tag: "query"}
+	formEncoder  = &ValuesEncoder{Tag: "form"}
+)
+
+// GQuery 将带 `query` tag 的struct编码为query字符串，按key排序，可直接拼接在url的 ? 之后
+func GQuery(v interface{}) (string, error) {
+	values, err := queryEncoder.Encode(v)
+	if err != nil {
+		return "", err
+	}
+	return values.Encode(), nil
+}
+
+// GForm 将带 `form` tag 的struct编码为form表单数据，可用于 PostForm
+func GForm(v interface{}) (url.Values, error) {
+	return formEncoder.Encode(v)
+}